@@ -37,7 +37,8 @@ const Signup = () => {
       username: formData.username,
       firstName: formData.firstName,
       lastName: formData.lastName,
-      password: formData.password
+      password: formData.password,
+      passwordConf: formData.confirmPassword
     }
 
     try {
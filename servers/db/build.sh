@@ -1,2 +0,0 @@
-docker build -t rjames187/db:1.0 .
-docker push rjames187/db:1.0
\ No newline at end of file
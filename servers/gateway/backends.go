@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"messaging-application/servers/gateway/events"
+	"messaging-application/servers/gateway/lockout"
+	"messaging-application/servers/gateway/ratelimit"
+	"messaging-application/servers/gateway/resetcodes"
+	"messaging-application/servers/gateway/sessions"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionExpiration is how long a session lasts without being used.
+const sessionExpiration = "1h"
+
+// sweepInterval is how often expired sessions and sign-in counters are
+// deleted when they are kept locally.
+const sweepInterval = time.Minute
+
+// backends holds the short-lived state that the gateway keeps either in
+// Redis, shared by every replica, or locally for a single gateway.
+type backends struct {
+	sessions       sessions.Store
+	resetCodes     resetcodes.Store
+	signInCounters lockout.Store
+	events         events.Bus
+	newLimiter     func(prefix string, rate ratelimit.Rate) ratelimit.Limiter
+	// ping checks the backend is reachable, and is nil for local backends.
+	ping  func(ctx context.Context) error
+	close func() error
+}
+
+// newRedisBackends keeps state in Redis at addr. A timeout of zero keeps
+// each store's default timeout. Each user's set of session IDs is kept for
+// tokenLifetime after it last changed.
+func newRedisBackends(addr string, timeout time.Duration, tokenLifetime time.Duration) backends {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	sessionStore := sessions.NewRedisStore(client, sessionExpiration)
+	sessionStore.IndexExpiration = tokenLifetime
+	resetCodes := resetcodes.NewRedisStore(client, 15*time.Minute)
+	signInCounters := lockout.NewRedisStore(client, 24*time.Hour)
+	bus := events.NewRedisBus(client, "events")
+	if timeout != 0 {
+		sessionStore.Timeout = timeout
+		resetCodes.Timeout = timeout
+		signInCounters.Timeout = timeout
+		bus.Timeout = timeout
+	}
+
+	return backends{
+		sessions:       &sessionStore,
+		resetCodes:     resetCodes,
+		signInCounters: signInCounters,
+		events:         bus,
+		newLimiter: func(prefix string, rate ratelimit.Rate) ratelimit.Limiter {
+			limiter := ratelimit.NewRedisLimiter(client, prefix, rate)
+			if timeout != 0 {
+				limiter.Timeout = timeout
+			}
+			return limiter
+		},
+		ping:  func(ctx context.Context) error { return client.Ping(ctx).Err() },
+		close: client.Close,
+	}
+}
+
+// newLocalBackends keeps sessions in the SQLite database db and everything
+// else in memory, for a single gateway running without Redis. Sessions are
+// kept in memory too if db is nil.
+func newLocalBackends(db *sql.DB, timeout time.Duration) (backends, error) {
+	var sessionStore sessions.Store
+	var stopSweeper func()
+	if db == nil {
+		memoryStore := sessions.NewMemoryStore(sessionExpiration)
+		stopSweeper = memoryStore.StartSweeper(sweepInterval)
+		sessionStore = memoryStore
+	} else {
+		sqliteStore, err := sessions.NewSQLiteStore(db, sessionExpiration)
+		if err != nil {
+			return backends{}, err
+		}
+		if timeout != 0 {
+			sqliteStore.Timeout = timeout
+		}
+		stopSweeper = sqliteStore.StartSweeper(sweepInterval)
+		sessionStore = sqliteStore
+	}
+
+	signInCounters := lockout.NewMemoryStore(24 * time.Hour)
+	stopCounterSweeper := signInCounters.StartSweeper(sweepInterval)
+
+	return backends{
+		sessions:       sessionStore,
+		resetCodes:     resetcodes.NewMemoryStore(15 * time.Minute),
+		signInCounters: signInCounters,
+		events:         events.NewMemoryBus(),
+		newLimiter: func(prefix string, rate ratelimit.Rate) ratelimit.Limiter {
+			return ratelimit.NewMemoryLimiter(rate)
+		},
+		close: func() error {
+			stopSweeper()
+			stopCounterSweeper()
+			return nil
+		},
+	}, nil
+}
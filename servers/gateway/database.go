@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"messaging-application/servers/gateway/migrations"
+	"messaging-application/servers/gateway/models/signins"
+	"messaging-application/servers/gateway/models/users"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// memoryPrefix starts DSNs that keep users in memory instead of in a
+// database, for demos. The rest of the DSN may name a JSON snapshot file
+// that users are loaded from at startup and saved to on shutdown.
+const memoryPrefix = "memory:"
+
+// sqlitePragmas are set on every connection to a SQLite database, so that
+// concurrent requests wait for each other's writes instead of failing.
+var sqlitePragmas = []string{"busy_timeout(5000)", "foreign_keys(1)", "journal_mode(WAL)"}
+
+// openDB opens the database in dsn. DSNs with a postgres:// or
+// postgresql:// scheme are for Postgres, DSNs starting with sqlite: name a
+// SQLite database file, and any other DSN is for MySQL. MySQL DSNs always
+// get parseTime=true, since the migrator scans DATETIME columns into
+// time.Time.
+func openDB(dsn string) (*sql.DB, migrations.Dialect, error) {
+	dialect := migrations.MySQL
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		dialect = migrations.Postgres
+	} else if path, ok := strings.CutPrefix(dsn, "sqlite:"); ok {
+		dialect = migrations.SQLite
+		dsn = sqliteDSN(path)
+	} else {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, dialect, err
+		}
+		cfg.ParseTime = true
+		dsn = cfg.FormatDSN()
+	}
+	db, err := sql.Open(dialect.Name, dsn)
+	return db, dialect, err
+}
+
+// sqliteDSN adds sqlitePragmas to the query of a SQLite DSN.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for _, pragma := range sqlitePragmas {
+		path += sep + "_pragma=" + pragma
+		sep = "&"
+	}
+	return path
+}
+
+// newSQLStores returns the user and sign-in stores for db. A timeout of
+// zero keeps the stores' default timeout.
+func newSQLStores(db *sql.DB, dialect migrations.Dialect, timeout time.Duration) (users.Store, signins.Store, error) {
+	if dialect.Name == migrations.Postgres.Name {
+		userStore, err := users.NewPostgresStore(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		signInStore, err := signins.NewPostgresStore(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if timeout != 0 {
+			userStore.Timeout = timeout
+			signInStore.Timeout = timeout
+		}
+		return &userStore, &signInStore, nil
+	}
+
+	if dialect.Name == migrations.SQLite.Name {
+		userStore, err := users.NewSQLiteStore(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		// SQLite accepts the MySQL sign-in queries as they are
+		signInStore, err := signins.NewMySQLStore(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if timeout != 0 {
+			userStore.Timeout = timeout
+			signInStore.Timeout = timeout
+		}
+		return &userStore, &signInStore, nil
+	}
+
+	userStore, err := users.NewMySQLStore(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	signInStore, err := signins.NewMySQLStore(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeout != 0 {
+		userStore.Timeout = timeout
+		signInStore.Timeout = timeout
+	}
+	return &userStore, &signInStore, nil
+}
+
+// newMemoryStores returns in-memory user and sign-in stores. If path names
+// an existing snapshot, the users are loaded from it.
+func newMemoryStores(path string) (*users.MemoryStore, signins.Store, error) {
+	userStore := users.NewMemoryStore()
+	if len(path) != 0 {
+		err := userStore.LoadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+	return userStore, signins.NewStubStore(), nil
+}
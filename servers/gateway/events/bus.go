@@ -0,0 +1,29 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event is the envelope services publish for the gateway to push to
+// connected clients. UserIDs lists the users allowed to see it; an empty
+// list means every connected user may.
+type Event struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	UserIDs []int           `json:"userIDs,omitempty"`
+}
+
+// DefaultTimeout is how long a RedisBus waits for each call by default.
+const DefaultTimeout = 3 * time.Second
+
+// Bus fans events out to every subscriber, which may live in other
+// gateway instances.
+type Bus interface {
+	Publish(ctx context.Context, event *Event) error
+	// Subscribe returns a channel of every event published from then on.
+	// ctx only bounds setting up the subscription.
+	Subscribe(ctx context.Context) (<-chan *Event, error)
+	Close() error
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+const subscriberBuffer = 64
+
+type MemoryBus struct {
+	mu          sync.Mutex
+	subscribers []chan *Event
+	closed      bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: []chan *Event{}}
+}
+
+// Publish sends event to every subscriber without waiting. A subscriber
+// whose buffer is full misses the event, so that one slow subscriber
+// cannot hold up publishers or Close.
+func (m *MemoryBus) Publish(ctx context.Context, event *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errors.New("event bus is closed")
+	}
+	for _, subscriber := range m.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			log.Printf("dropping %s event for a slow subscriber", event.Type)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryBus) Subscribe(ctx context.Context) (<-chan *Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, errors.New("event bus is closed")
+	}
+	subscriber := make(chan *Event, subscriberBuffer)
+	m.subscribers = append(m.subscribers, subscriber)
+	return subscriber, nil
+}
+
+func (m *MemoryBus) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		for _, subscriber := range m.subscribers {
+			close(subscriber)
+		}
+	}
+	return nil
+}
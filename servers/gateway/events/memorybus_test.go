@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryBusFanOut(t *testing.T) {
+	bus := NewMemoryBus()
+
+	first, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := &Event{Type: "message-new", Payload: json.RawMessage(`{"id":1}`), UserIDs: []int{1, 2}}
+	err = bus.Publish(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subscription := range []<-chan *Event{first, second} {
+		received := <-subscription
+		if received.Type != event.Type || string(received.Payload) != string(event.Payload) {
+			t.Errorf("Expected %v but got %v", event, received)
+		}
+	}
+}
+
+func TestMemoryBusClose(t *testing.T) {
+	bus := NewMemoryBus()
+
+	subscription, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bus.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-subscription; ok {
+		t.Error("Expected subscription to be closed")
+	}
+	if err := bus.Publish(context.Background(), &Event{Type: "message-new"}); err == nil {
+		t.Error("Expected publishing to a closed bus to fail")
+	}
+	if _, err := bus.Subscribe(context.Background()); err == nil {
+		t.Error("Expected subscribing to a closed bus to fail")
+	}
+}
+
+func TestMemoryBusSlowSubscriber(t *testing.T) {
+	bus := NewMemoryBus()
+
+	slow, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slow subscriber never reads, but publishing carries on
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range subscriberBuffer * 2 {
+			err := bus.Publish(context.Background(), &Event{Type: "message-new", Payload: json.RawMessage(strconv.Itoa(i))})
+			if err != nil {
+				t.Error(err)
+			}
+			<-fast
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected publishing not to block on a slow subscriber")
+	}
+
+	if len(slow) != subscriberBuffer {
+		t.Errorf("Expected the slow subscriber to keep %d events but got %d", subscriberBuffer, len(slow))
+	}
+	err = bus.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
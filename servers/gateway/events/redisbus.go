@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"messaging-application/servers/gateway/timeout"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisBus struct {
+	rdb     *redis.Client
+	channel string
+	mu      sync.Mutex
+	subs    []*redis.PubSub
+
+	// Timeout bounds each call to Redis. Zero leaves calls bounded only
+	// by their context.
+	Timeout time.Duration
+}
+
+func NewRedisBus(client *redis.Client, channel string) *RedisBus {
+	return &RedisBus{
+		rdb:     client,
+		channel: channel,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func (rb *RedisBus) Publish(ctx context.Context, event *Event) error {
+	ctx, cancel := timeout.Context(ctx, rb.Timeout)
+	defer cancel()
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return rb.rdb.Publish(ctx, rb.channel, message).Err()
+}
+
+func (rb *RedisBus) Subscribe(ctx context.Context) (<-chan *Event, error) {
+	ctx, cancel := timeout.Context(ctx, rb.Timeout)
+	defer cancel()
+
+	pubsub := rb.rdb.Subscribe(ctx, rb.channel)
+	// Wait for the subscription to be confirmed so no event published
+	// after Subscribe returns can be missed.
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	rb.mu.Lock()
+	rb.subs = append(rb.subs, pubsub)
+	rb.mu.Unlock()
+
+	events := make(chan *Event, subscriberBuffer)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			event := &Event{}
+			err := json.Unmarshal([]byte(msg.Payload), event)
+			if err != nil {
+				log.Printf("error decoding event: %v", err)
+				continue
+			}
+			events <- event
+		}
+	}()
+	return events, nil
+}
+
+func (rb *RedisBus) Close() error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	var err error
+	for _, pubsub := range rb.subs {
+		if closeErr := pubsub.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	rb.subs = nil
+	return err
+}
@@ -0,0 +1,61 @@
+//go:build !no_db
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func getClient() *redis.Client {
+	addr := os.Getenv("REDISADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+	return client
+}
+
+var redisClient = getClient()
+
+func TestRedisBusFanOut(t *testing.T) {
+	// Two buses on one channel stand in for two gateway instances
+	publisher := NewRedisBus(redisClient, "events-test")
+	defer publisher.Close()
+	subscriber := NewRedisBus(redisClient, "events-test")
+	defer subscriber.Close()
+
+	subscription, err := subscriber.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = publisher.Publish(context.Background(), &Event{Type: "message-new", Payload: json.RawMessage(`{"id":1}`), UserIDs: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-subscription:
+		if event.Type != "message-new" || string(event.Payload) != `{"id":1}` || len(event.UserIDs) != 1 {
+			t.Errorf("Unexpected event %v", event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("Expected event to be received")
+	}
+
+	err = subscriber.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-subscription; ok {
+		t.Error("Expected subscription to be closed")
+	}
+}
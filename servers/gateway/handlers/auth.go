@@ -1,59 +1,72 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"messaging-application/servers/gateway/models/users"
 	"messaging-application/servers/gateway/sessions"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const maxSearchResults = 20
+
 func (ctx *HandlerContext) UsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		ctx.searchUsers(w, r)
+		return
+	}
+
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		writeError(w, r, errUnsupportedMediaType)
 		return
 	}
 
 	newUser := &users.NewUser{}
 	err := json.NewDecoder(r.Body).Decode(newUser)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, r, errInvalidPayload)
 		return
 	}
 
 	err = newUser.Validate()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_user", err.Error()))
 		return
 	}
 
 	user, err := newUser.ToUser()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	user, err = ctx.UserStore.Insert(user)
+	user, err = ctx.UserStore.Insert(r.Context(), user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	sessionState, err := GetSerializedSessionState(user)
+	sessionState, err := ctx.GetSerializedSessionState(user, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	sessionToken, err := sessions.BeginSession(sessionState, ctx.Secret, ctx.SessionStore)
+	sessionToken, err := sessions.BeginSession(r.Context(), user.ID, sessionState, ctx.Keys, ctx.SessionStore)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -63,24 +76,36 @@ func (ctx *HandlerContext) UsersHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(user)
 }
 
-func (ctx *HandlerContext) SpecificUserHandler(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+// searchUsers responds with the users whose username, first name or last
+// name starts with the q query string parameter.
+func (ctx *HandlerContext) searchUsers(w http.ResponseWriter, r *http.Request) {
+	_, err := ctx.getSessionState(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	prefix := strings.TrimSpace(r.URL.Query().Get("q"))
+	if prefix == "" {
+		writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_query", "Search query cannot be blank"))
 		return
 	}
 
-	sessionToken := authHeader[7:]
-	serializedSessionState, err := sessions.GetSessionState(sessionToken, ctx.Secret, ctx.SessionStore)
+	found, err := ctx.UserStore.GetByPrefix(r.Context(), prefix, maxSearchResults)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	sessionState := &SessionState{}
-	err = json.Unmarshal([]byte(serializedSessionState), sessionState)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(found)
+}
+
+func (ctx *HandlerContext) SpecificUserHandler(w http.ResponseWriter, r *http.Request) {
+	sessionState, err := ctx.getSessionState(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -92,102 +117,299 @@ func (ctx *HandlerContext) SpecificUserHandler(w http.ResponseWriter, r *http.Re
 	} else {
 		userID, err = strconv.Atoi(userIDParam)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_user_id", "Invalid user ID"))
 			return
 		}
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		user, err := ctx.UserStore.GetByID(userID)
-		if err == nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(user)
-		} else if err.Error() == "user was not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		user, err := ctx.UserStore.GetByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(user)
 	case http.MethodPatch:
 		if userID != loggedInUserID {
-			http.Error(w, "You are not allowed to update this user", http.StatusForbidden)
+			writeError(w, r, newAPIError(http.StatusForbidden, "forbidden", "You are not allowed to update this user"))
 			return
 		}
 
 		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			writeError(w, r, errUnsupportedMediaType)
 			return
 		}
 
 		userUpdate := &users.Updates{}
 		err := json.NewDecoder(r.Body).Decode(userUpdate)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			writeError(w, r, errInvalidPayload)
 			return
 		}
 
-		user, err := ctx.UserStore.GetByID(userID)
-		if err != nil && err.Error() == "user was not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		err = userUpdate.Validate()
+		if err != nil {
+			writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_user", err.Error()))
 			return
-		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+
+		user, err := ctx.UserStore.GetByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
-		user.ApplyUpdates(userUpdate)
 
-		updatedUser, err := ctx.UserStore.Update(userID, user)
+		// The verification email is only sent once the rest of the
+		// update has been saved, so a failed update never mails a token
+		newEmail := users.NormalizeEmail(userUpdate.Email)
+		changingEmail := newEmail != "" && newEmail != user.Email
+		if changingEmail {
+			err = ctx.checkEmailAvailable(r, user.ID, newEmail)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		err = user.ApplyUpdates(userUpdate)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
+		updatedUser, err := ctx.UserStore.Update(r.Context(), userID, user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if userUpdate.Password != "" {
+			currentID, err := ctx.getSessionID(r)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			// The password has already changed, so sign out the other
+			// devices even if the client hangs up
+			err = sessions.EndOtherSessions(context.WithoutCancel(r.Context()), userID, currentID, ctx.SessionStore)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		err = ctx.refreshSessions(r, updatedUser)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if changingEmail {
+			err = ctx.requestEmailChange(updatedUser, newEmail)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(updatedUser)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+// checkEmailAvailable returns ErrDuplicate if a user other than the one
+// with userID has newEmail.
+func (ctx *HandlerContext) checkEmailAvailable(r *http.Request, userID int, newEmail string) error {
+	existing, err := ctx.UserStore.GetByEmail(r.Context(), newEmail)
+	if err == nil && existing.ID != userID {
+		return fmt.Errorf("%w with that email", users.ErrDuplicate)
+	} else if err != nil && !errors.Is(err, users.ErrUserNotFound) {
+		return err
+	}
+	return nil
+}
+
+// requestEmailChange mails a verification token to the new address. The
+// user's email only changes once the token is posted to
+// EmailVerificationHandler.
+func (ctx *HandlerContext) requestEmailChange(user *users.User, newEmail string) error {
+	token, err := users.NewEmailChange(user, newEmail).Token(ctx.Keys.Secret(users.EmailChangeKeyPurpose))
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse this token to confirm %s as your new email address. "+
+		"It expires in %s.\n\n%s\n\nIf you did not ask to change your email, ignore this message.",
+		user.FullName(), newEmail, users.EmailChangeTTL, token)
+	return ctx.Mailer.Send(newEmail, "Confirm your new email address", body)
+}
+
+// EmailVerificationHandler commits a pending email change for the
+// signed-in user. Every other session is signed out afterwards.
+func (ctx *HandlerContext) EmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+
+	sessionState, err := ctx.getSessionState(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	verification := &struct {
+		Token string `json:"token"`
+	}{}
+	err = json.NewDecoder(r.Body).Decode(verification)
+	if err != nil {
+		writeError(w, r, errInvalidPayload)
+		return
+	}
+
+	change, err := users.ParseEmailChangeToken(verification.Token, ctx.Keys.Secrets(users.EmailChangeKeyPurpose))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := ctx.UserStore.GetByID(r.Context(), sessionState.User.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = change.Apply(user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	updatedUser, err := ctx.UserStore.Update(r.Context(), user.ID, user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	currentID, err := ctx.getSessionID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// The email has already changed, so sign out the other devices even
+	// if the client hangs up
+	err = sessions.EndOtherSessions(context.WithoutCancel(r.Context()), user.ID, currentID, ctx.SessionStore)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = ctx.refreshSessions(r, updatedUser)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedUser)
+}
+
+// SessionInfo describes one of the user's active sessions.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	Start     time.Time `json:"start"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	Current   bool      `json:"current"`
 }
 
 func (ctx *HandlerContext) SessionsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodPost {
+	if r.Method == http.MethodGet {
+		ctx.listSessions(w, r)
+	} else if r.Method == http.MethodPost {
 		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			writeError(w, r, errUnsupportedMediaType)
 			return
 		}
 
 		credentials := &users.Credentials{}
 		err := json.NewDecoder(r.Body).Decode(credentials)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			writeError(w, r, errInvalidPayload)
 			return
 		}
 
-		user, err := ctx.UserStore.GetByEmail(credentials.Email)
-		if err != nil && err.Error() == "user was not found" {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		email := users.NormalizeEmail(credentials.Email)
+		user, err := ctx.UserStore.GetByEmail(r.Context(), email)
+		if err != nil && !errors.Is(err, users.ErrUserNotFound) {
+			writeError(w, r, err)
 			return
-		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		found := err == nil
+
+		var userID int
+		if found {
+			userID = user.ID
+		}
+
+		emailKey := "email:" + email
+		ipKey := "ip:" + ctx.clientIP(r)
+		wait, err := ctx.SignInTracker.Check(r.Context(), emailKey, ipKey)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if wait > 0 {
+			ctx.recordSignIn(r, userID, false)
+			writeTooManyRequests(w, r, wait)
 			return
 		}
 
-		if !user.Authenticate(credentials.Password) {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if !found || !user.Authenticate(credentials.Password) {
+			// Count the failure first, so that the lockout holds even if
+			// the attempt cannot be recorded
+			err = ctx.SignInTracker.Failure(r.Context(), emailKey, ipKey)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			ctx.recordSignIn(r, userID, false)
+			writeError(w, r, errInvalidCredentials)
 			return
 		}
 
-		sessionState, err := GetSerializedSessionState(user)
+		ctx.recordSignIn(r, userID, true)
+
+		// Only the account's counter is cleared. Clearing the IP's would let
+		// an attacker reset it by signing in to an account of their own.
+		err = ctx.SignInTracker.Success(r.Context(), emailKey)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		sessionState, err := ctx.GetSerializedSessionState(user, r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		sessionToken, err := sessions.BeginSession(sessionState, ctx.Secret, ctx.SessionStore)
+		sessionToken, err := sessions.BeginSession(r.Context(), user.ID, sessionState, ctx.Keys, ctx.SessionStore)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -196,33 +418,105 @@ func (ctx *HandlerContext) SessionsHandler(w http.ResponseWriter, r *http.Reques
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(user)
 	} else {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 	}
 }
 
-func (ctx *HandlerContext) SpecificSessionHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodDelete {
-		authHeader := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+// listSessions responds with the signed-in user's active sessions. IDs
+// whose session has expired are dropped from the user's index.
+func (ctx *HandlerContext) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessionState, err := ctx.getSessionState(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	currentID, err := ctx.getSessionID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userID := sessionState.User.ID
+	sessionIDs, err := ctx.SessionStore.GetSessionIDs(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	infos := []*SessionInfo{}
+	for _, sessionID := range sessionIDs {
+		serialized, err := ctx.SessionStore.Get(r.Context(), sessionID)
+		if errors.Is(err, sessions.ErrStateNotFound) {
+			ctx.SessionStore.RemoveSessionID(r.Context(), userID, sessionID)
+			continue
+		} else if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		state, err := parseSessionState(serialized)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		if r.PathValue("SessionID") != "mine" {
-			http.Error(w, "You are not allowed to delete this session", http.StatusForbidden)
+		infos = append(infos, &SessionInfo{
+			ID:        sessionID,
+			Start:     state.Start,
+			UserAgent: state.UserAgent,
+			IP:        state.IP,
+			Current:   sessionID == currentID,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Start.Before(infos[j].Start)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(infos)
+}
+
+// SpecificSessionHandler signs the user out of the current session
+// ("mine"), of every session ("all"), or of one of their other sessions
+// by ID.
+func (ctx *HandlerContext) SpecificSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		sessionState, err := ctx.getSessionState(r)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		sessionToken := authHeader[7:]
-		err := sessions.EndSession(sessionToken, ctx.SessionStore)
+		userID := sessionState.User.ID
+		switch sessionID := r.PathValue("SessionID"); sessionID {
+		case "mine":
+			sessionToken, _ := getSessionToken(r)
+			err = sessions.EndSession(r.Context(), userID, sessionToken, ctx.SessionStore)
+		case "all":
+			err = sessions.EndAllSessions(r.Context(), userID, ctx.SessionStore)
+		default:
+			var sessionIDs []string
+			sessionIDs, err = ctx.SessionStore.GetSessionIDs(r.Context(), userID)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if !slices.Contains(sessionIDs, sessionID) {
+				writeError(w, r, newAPIError(http.StatusForbidden, "forbidden", "You are not allowed to delete this session"))
+				return
+			}
+			err = sessions.RevokeSession(r.Context(), userID, sessionID, ctx.SessionStore)
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 		w.Write([]byte("Signed out"))
 	} else {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 	}
 }
@@ -2,32 +2,62 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"messaging-application/servers/gateway/lockout"
+	"messaging-application/servers/gateway/mail"
+	"messaging-application/servers/gateway/models/signins"
 	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/notify"
+	"messaging-application/servers/gateway/ratelimit"
+	"messaging-application/servers/gateway/resetcodes"
 	"messaging-application/servers/gateway/sessions"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 )
 
 var ctx *HandlerContext
+var mailbox *bytes.Buffer
+var auditLog *bytes.Buffer
 
 const secret = "c2VjcmV0"
 
+var keys, _ = sessions.NewKeyring(sessions.Key{Secret: secret}, nil)
+
 func newContext() *http.ServeMux {
+	mailbox = &bytes.Buffer{}
+	auditLog = &bytes.Buffer{}
 	ctx = &HandlerContext{
-		secret,
-		sessions.NewMemoryStore(),
-		users.NewStubStore(),
+		Keys:         keys,
+		SessionStore: sessions.NewMemoryStore("1h"),
+		UserStore:    users.NewMemoryStore(),
+		Notifier:     notify.NewHub(),
+		Mailer:       mail.NewLogMailer(mailbox),
+		ResetCodes:   resetcodes.NewMemoryStore(time.Minute),
+		ResetLimiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Limit: 5, Period: time.Minute}),
+		SignInTracker: lockout.NewTracker(lockout.NewMemoryStore(time.Hour), 5, time.Minute, time.Hour,
+			log.New(auditLog, "", 0)),
+		SignInStore:    signins.NewStubStore(),
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/users", ctx.UsersHandler)
 	mux.HandleFunc("/v1/users/{UserID}", ctx.SpecificUserHandler)
+	mux.HandleFunc("/v1/users/me/email/verify", ctx.EmailVerificationHandler)
+	mux.HandleFunc("/v1/users/me/signins", ctx.SignInsHandler)
 	mux.HandleFunc("/v1/sessions", ctx.SessionsHandler)
 	mux.HandleFunc("/v1/sessions/{SessionID}", ctx.SpecificSessionHandler)
+	mux.HandleFunc("/v1/resetcodes", ctx.ResetCodesHandler)
+	mux.HandleFunc("/v1/passwords/{Email}", ctx.PasswordsHandler)
+	mux.HandleFunc("/v1/ws", ctx.WebSocketHandler)
 	return mux
 }
 
@@ -35,10 +65,12 @@ func TestNewUser(t *testing.T) {
 	handler := newContext()
 
 	jsonData, err := json.Marshal(&users.NewUser{
-		Password:  "password343",
-		Email:     "valid_email@example.com",
-		FirstName: "Jon",
-		LastName:  "Doe",
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -70,7 +102,7 @@ func TestNewUserErrors(t *testing.T) {
 	handler := newContext()
 
 	// Wrong Method
-	req, err := http.NewRequest(http.MethodGet, "/v1/users", nil)
+	req, err := http.NewRequest(http.MethodPut, "/v1/users", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -102,19 +134,43 @@ func TestNewUserErrors(t *testing.T) {
 			Password: "",
 		},
 		{
-			Password: "not_empty",
-			Email:    "invalid_email",
+			Password:     "password343",
+			PasswordConf: "password343",
+			Username:     "jondoe",
+			Email:        "invalid_email",
+		},
+		{
+			Password:     "password343",
+			PasswordConf: "password343",
+			Username:     "jondoe",
+			Email:        "valid_email@example.com",
+			FirstName:    "345346invalid_name",
+		},
+		{
+			Password:     "password343",
+			PasswordConf: "password343",
+			Username:     "jondoe",
+			Email:        "valid_email@example.com",
+			FirstName:    "Validname",
+			LastName:     "8y78fa6sf8invalid_name",
+		},
+		{
+			Password:     "password343",
+			PasswordConf: "password343",
+			Username:     "jon doe",
+			Email:        "valid_email@example.com",
 		},
 		{
-			Password:  "not_empty",
-			Email:     "valid_email@example.com",
-			FirstName: "345346invalid_name",
+			Password:     "password343",
+			PasswordConf: "password344",
+			Username:     "jondoe",
+			Email:        "valid_email@example.com",
 		},
 		{
-			Password:  "not_empty",
-			Email:     "valid_email@example.com",
-			FirstName: "Validname",
-			LastName:  "8y78fa6sf8invalid_name",
+			Password:     "short1",
+			PasswordConf: "short1",
+			Username:     "jondoe",
+			Email:        "valid_email@example.com",
 		},
 	}
 
@@ -138,15 +194,48 @@ func TestNewUserErrors(t *testing.T) {
 	}
 }
 
+func TestNewUserDuplicate(t *testing.T) {
+	handler := newContext()
+
+	duplicates := []*users.NewUser{
+		{Password: "password343", PasswordConf: "password343", Email: "valid_email@example.com", Username: "jondoe"},
+		{Password: "password343", PasswordConf: "password343", Email: "other_email@example.com", Username: "jondoe"},
+		{Password: "password343", PasswordConf: "password343", Email: "valid_email@example.com", Username: "janedoe"},
+	}
+	expected := []int{http.StatusCreated, http.StatusConflict, http.StatusConflict}
+
+	for i, nu := range duplicates {
+		jsonData, err := json.Marshal(nu)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != expected[i] {
+			t.Errorf("Expected status %d, got %d", expected[i], status)
+		}
+	}
+}
+
 func TestSpecificUser(t *testing.T) {
 	handler := newContext()
 
 	// create new user
 	jsonData, err := json.Marshal(&users.NewUser{
-		Password:  "password343",
-		Email:     "valid_email@example.com",
-		FirstName: "Jon",
-		LastName:  "Doe",
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -220,15 +309,317 @@ func TestSpecificUser(t *testing.T) {
 	}
 }
 
+func TestUpdateUserSessions(t *testing.T) {
+	handler := newContext()
+
+	// create new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	authHeader := rr.Header().Get("Authorization")
+
+	// sign in from another device
+	jsonData, err = json.Marshal(&users.Credentials{
+		Password: "password343",
+		Email:    "valid_email@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	otherAuthHeader := rr.Header().Get("Authorization")
+
+	// change profile fields
+	jsonData, err = json.Marshal(&users.Updates{FirstName: "Jonathan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", otherAuthHeader)
+
+	sessionState, err := ctx.getSessionState(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessionState.User.FirstName != "Jonathan" {
+		t.Errorf("Expected other session to be refreshed, got first name %s", sessionState.User.FirstName)
+	}
+
+	// change password
+	jsonData, err = json.Marshal(&users.Updates{Password: "newpassword343"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+
+	expected := map[string]int{
+		authHeader:      http.StatusOK,
+		otherAuthHeader: http.StatusUnauthorized,
+	}
+	for header, status := range expected {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", header)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != status {
+			t.Errorf("Expected status %d, got %d", status, rr.Code)
+		}
+	}
+}
+
+// lastMailedToken returns the verification token in the last email sent.
+func lastMailedToken(t *testing.T) string {
+	lines := strings.Split(strings.TrimSpace(mailbox.String()), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.Count(lines[i], ".") == 1 && !strings.Contains(lines[i], " ") {
+			return lines[i]
+		}
+	}
+	t.Fatalf("no token found in mailbox: %s", mailbox.String())
+	return ""
+}
+
+func TestEmailChange(t *testing.T) {
+	handler := newContext()
+
+	// create two users
+	var authHeader string
+	for _, email := range []string{"valid_email@example.com", "taken@example.com"} {
+		jsonData, err := json.Marshal(&users.NewUser{
+			Password:     "password343",
+			PasswordConf: "password343",
+			Email:        email,
+			Username:     strings.Split(email, "@")[0],
+			FirstName:    "Jon",
+			LastName:     "Doe",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatal(rr.Body.String())
+		}
+		if authHeader == "" {
+			authHeader = rr.Header().Get("Authorization")
+		}
+	}
+
+	// request changes to an invalid, a taken and a free address
+	expected := map[string]int{
+		"not-an-email":          http.StatusBadRequest,
+		"taken@example.com":     http.StatusConflict,
+		"new_email@example.com": http.StatusOK,
+	}
+	for email, status := range expected {
+		jsonData, err := json.Marshal(&users.Updates{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", authHeader)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != status {
+			t.Errorf("Expected status %d for %s, got %d", status, email, rr.Code)
+		}
+	}
+
+	if !strings.Contains(mailbox.String(), "To: new_email@example.com") {
+		t.Fatalf("Expected verification email to new address, got: %s", mailbox.String())
+	}
+	_, err := ctx.UserStore.GetByEmail(context.Background(), "valid_email@example.com")
+	if err != nil {
+		t.Errorf("Expected email to be unchanged before verification: %s", err)
+	}
+
+	// verify with a bad token, the mailed token, then the same token again
+	token := lastMailedToken(t)
+	tokens := []string{"bad.token", token, token}
+	statuses := []int{http.StatusBadRequest, http.StatusOK, http.StatusBadRequest}
+	for i, tok := range tokens {
+		jsonData, err := json.Marshal(map[string]string{"token": tok})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users/me/email/verify", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", authHeader)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != statuses[i] {
+			t.Errorf("Expected status %d, got %d", statuses[i], rr.Code)
+		}
+	}
+
+	user, err := ctx.UserStore.GetByEmail(context.Background(), "new_email@example.com")
+	if err != nil {
+		t.Fatalf("Expected email to change after verification: %s", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Expected user 1 to own the new email, got %d", user.ID)
+	}
+}
+
+// failingUpdateStore fails to save any update.
+type failingUpdateStore struct {
+	users.Store
+}
+
+func (s failingUpdateStore) Update(ctx context.Context, id int, user *users.User) (*users.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestEmailChangeFailedUpdate(t *testing.T) {
+	handler := newContext()
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+	authHeader := rr.Header().Get("Authorization")
+	ctx.UserStore = failingUpdateStore{ctx.UserStore}
+
+	// the rest of the update fails after the new email was checked
+	jsonData, err = json.Marshal(&users.Updates{Email: "new_email@example.com", FirstName: "Jonathan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err = http.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if strings.Contains(mailbox.String(), "To: new_email@example.com") {
+		t.Errorf("Expected no verification email when the update fails, got: %s", mailbox.String())
+	}
+}
+
 func TestSpecificUserErrors(t *testing.T) {
 	handler := newContext()
 
 	// create new user
 	jsonData, err := json.Marshal(&users.NewUser{
-		Password:  "password343",
-		Email:     "valid_email@example.com",
-		FirstName: "Jon",
-		LastName:  "Doe",
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -325,10 +716,12 @@ func TestSessionsFlow(t *testing.T) {
 
 	// Create a new user
 	jsonData, err := json.Marshal(&users.NewUser{
-		Password:  "password343",
-		Email:     "valid_email@example.com",
-		FirstName: "Jon",
-		LastName:  "Doe",
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -400,15 +793,161 @@ func TestSessionsFlow(t *testing.T) {
 	}
 }
 
+func TestSessionManagement(t *testing.T) {
+	handler := newContext()
+
+	// Create a new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	firstAuth := rr.Header().Get("Authorization")
+
+	// Sign in from two other devices
+	jsonData, err = json.Marshal(&users.Credentials{
+		Password: "password343",
+		Email:    "valid_email@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var otherAuths []string
+	for _, agent := range []string{"phone", "tablet"} {
+		req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", agent)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+		req.RemoteAddr = "10.0.0.2:4000"
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatal(rr.Body.String())
+		}
+		otherAuths = append(otherAuths, rr.Header().Get("Authorization"))
+	}
+
+	// List sessions
+	req, err = http.NewRequest(http.MethodGet, "/v1/sessions", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", firstAuth)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+
+	infos := []*SessionInfo{}
+	err = json.NewDecoder(rr.Body).Decode(&infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d", len(infos))
+	}
+	if !infos[0].Current || infos[1].Current || infos[2].Current {
+		t.Errorf("Expected only the first session to be current")
+	}
+	if infos[1].UserAgent != "phone" || infos[1].IP != "203.0.113.7" {
+		t.Errorf("Expected phone session from 203.0.113.7, got %s from %s", infos[1].UserAgent, infos[1].IP)
+	}
+
+	// Revoke the phone session
+	req, err = http.NewRequest(http.MethodDelete, "/v1/sessions/"+infos[1].ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", firstAuth)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", otherAuths[0])
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	// Sign out everywhere
+	req, err = http.NewRequest(http.MethodDelete, "/v1/sessions/all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", firstAuth)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+
+	for _, authHeader := range []string{firstAuth, otherAuths[1]} {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authHeader)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+	}
+}
+
 func TestSessionsErrors(t *testing.T) {
 	handler := newContext()
 
 	// Create a new user
 	jsonData, err := json.Marshal(&users.NewUser{
-		Password:  "password343",
-		Email:     "valid_email@example.com",
-		FirstName: "Jon",
-		LastName:  "Doe",
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -528,3 +1067,156 @@ func TestSessionsErrors(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
 	}
 }
+
+func TestSearchUsers(t *testing.T) {
+	handler := newContext()
+
+	// Create users to search for
+	var authHeader string
+	for _, nu := range []*users.NewUser{
+		{Password: "password343", PasswordConf: "password343", Email: "bob@example.com", Username: "bobby99", FirstName: "Bob", LastName: "Doe"},
+		{Password: "password343", PasswordConf: "password343", Email: "mary@example.com", Username: "mary", FirstName: "Mary", LastName: "Bobson"},
+		{Password: "password343", PasswordConf: "password343", Email: "alice@example.com", Username: "alice", FirstName: "Alice", LastName: "Smith"},
+	} {
+		jsonData, err := json.Marshal(nu)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatal(rr.Body.String())
+		}
+		authHeader = rr.Header().Get("Authorization")
+	}
+
+	// Search by prefix
+	req, err := http.NewRequest(http.MethodGet, "/v1/users?q=BOB", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	found := []*users.User{}
+	err = json.Unmarshal(rr.Body.Bytes(), &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Username != "bobby99" || found[1].Username != "mary" {
+		t.Errorf("Expected bobby99 and mary to be found, got %s", rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "@example.com") {
+		t.Error("Expected email addresses not to be returned")
+	}
+
+	// Missing auth header
+	req, err = http.NewRequest(http.MethodGet, "/v1/users?q=bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	// Blank query
+	req, err = http.NewRequest(http.MethodGet, "/v1/users?q=%20", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestSignInLockout(t *testing.T) {
+	handler := newContext()
+
+	// Create a new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	// Fail five times, then try the right password
+	passwords := []string{"wrong1", "wrong2", "wrong3", "wrong4", "wrong5", "password343"}
+	expected := []int{
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusTooManyRequests,
+	}
+	for i, password := range passwords {
+		jsonData, err = json.Marshal(&users.Credentials{
+			Password: password,
+			Email:    "valid_email@example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		req.RemoteAddr = "10.0.0.2:4000"
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != expected[i] {
+			t.Errorf("Expected status %d, got %d", expected[i], status)
+		}
+	}
+
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After of 60 seconds, got %q", rr.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(auditLog.String(), "lockout key=email:valid_email@example.com") {
+		t.Errorf("Expected an audit entry for the lockout, got %q", auditLog.String())
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma separated list of the proxies in front
+// of the gateway, each an IP address or a CIDR prefix, as in
+// 10.0.0.0/8,192.0.2.1.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// trustedProxy reports whether addr is one of the proxies in front of the
+// gateway.
+func (ctx *HandlerContext) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range ctx.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an address as it appears in RemoteAddr or
+// X-Forwarded-For, dropping any zone and IPv4-in-IPv6 mapping.
+func parseIP(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+// clientIP returns the address of the client that made the request. Any
+// client can send X-Forwarded-For, so it is only believed when the
+// connection comes from a trusted proxy, and then only up to the right-most
+// hop that is not itself a trusted proxy.
+func (ctx *HandlerContext) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := parseIP(host)
+	if err != nil {
+		return host
+	}
+	if !ctx.trustedProxy(addr) {
+		return addr.String()
+	}
+
+	hops := []string{}
+	for _, forwarded := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(forwarded, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		// A malformed hop was not written by a trusted proxy, so nothing
+		// before it can be believed either
+		hop, err := parseIP(hops[i])
+		if err != nil {
+			break
+		}
+		addr = hop
+		if !ctx.trustedProxy(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1,::ffff:198.51.100.7,2001:db8::/32,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10.0.0.0/8", "192.0.2.1/32", "198.51.100.7/32", "2001:db8::/32"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Expected %v but got %v", expected, prefixes)
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != expected[i] {
+			t.Errorf("Expected %s but got %s", expected[i], prefix)
+		}
+	}
+
+	prefixes, err = ParseTrustedProxies("")
+	if err != nil || len(prefixes) != 0 {
+		t.Errorf("Expected no trusted proxies but got %v, %v", prefixes, err)
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0.1,nope"} {
+		_, err = ParseTrustedProxies(invalid)
+		if err == nil {
+			t.Errorf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	ctx := &HandlerContext{TrustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{"no proxy", "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"spoofed by an untrusted client", "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:4000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed through a trusted proxy", "10.0.0.2:4000", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"chain of trusted proxies", "10.0.0.2:4000", []string{"198.51.100.1, 203.0.113.7, 10.0.0.3", "10.0.0.4"}, "203.0.113.7"},
+		{"only trusted hops", "10.0.0.2:4000", []string{"10.0.0.3"}, "10.0.0.3"},
+		{"malformed hop", "10.0.0.2:4000", []string{"198.51.100.1, nonsense, 10.0.0.3"}, "10.0.0.3"},
+		{"trusted proxy without the header", "10.0.0.2:4000", nil, "10.0.0.2"},
+		{"mapped IPv4 proxy", "[::ffff:10.0.0.2]:4000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"IPv6 proxy with a zone", "[fd00::1%eth0]:4000", []string{"2001:db8::7"}, "2001:db8::7"},
+		{"remote address without a port", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.RemoteAddr = c.remoteAddr
+			for _, forwarded := range c.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+
+			if ip := ctx.clientIP(req); ip != c.expected {
+				t.Errorf("Expected %s but got %s", c.expected, ip)
+			}
+		})
+	}
+}
@@ -1,20 +1,39 @@
 package handlers
 
 import (
+	"messaging-application/servers/gateway/lockout"
+	"messaging-application/servers/gateway/mail"
+	"messaging-application/servers/gateway/models/signins"
 	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/notify"
+	"messaging-application/servers/gateway/ratelimit"
+	"messaging-application/servers/gateway/resetcodes"
 	"messaging-application/servers/gateway/sessions"
+	"net/netip"
 )
 
 type HandlerContext struct {
-	Secret       string         `json:"secret"`
-	SessionStore sessions.Store `json:"sessionStore"`
-	UserStore    users.Store    `json:"userStore"`
+	// Keys signs session tokens, reset codes and email change tokens
+	Keys          *sessions.Keyring `json:"keys"`
+	SessionStore  sessions.Store    `json:"sessionStore"`
+	UserStore     users.Store       `json:"userStore"`
+	Notifier      *notify.Hub       `json:"notifier"`
+	Mailer        mail.Mailer       `json:"mailer"`
+	ResetCodes    resetcodes.Store  `json:"resetCodes"`
+	ResetLimiter  ratelimit.Limiter `json:"resetLimiter"`
+	SignInTracker *lockout.Tracker  `json:"signInTracker"`
+	SignInStore   signins.Store     `json:"signInStore"`
+	// TrustedProxies are the proxies whose X-Forwarded-For headers are
+	// believed when working out the client's address
+	TrustedProxies []netip.Prefix `json:"trustedProxies"`
 }
 
-func NewHandlerContext(secret string, sessionStore sessions.Store, userStore users.Store) *HandlerContext {
+func NewHandlerContext(keys *sessions.Keyring, sessionStore sessions.Store, userStore users.Store, notifier *notify.Hub, mailer mail.Mailer) *HandlerContext {
 	return &HandlerContext{
-		Secret:       secret,
+		Keys:         keys,
 		SessionStore: sessionStore,
 		UserStore:    userStore,
+		Notifier:     notifier,
+		Mailer:       mailer,
 	}
 }
@@ -39,11 +39,12 @@ func TestCORSHandlerWithPreFlight(t *testing.T) {
 	if rr.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
 		t.Errorf("Expected Access-Control-Allow-Methods header to be 'GET, POST, PUT, PATCH, DELETE, OPTIONS', got '%s'", rr.Header().Get("Access-Control-Allow-Methods"))
 	}
-	if rr.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" {
-		t.Errorf("Expected Access-Control-Allow-Headers header to be 'Content-Type, Authorization', got '%s'", rr.Header().Get("Access-Control-Allow-Headers"))
+	if rr.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization, X-Request-ID" {
+		t.Errorf("Expected Access-Control-Allow-Headers header to be 'Content-Type, Authorization, X-Request-ID', got '%s'", rr.Header().Get("Access-Control-Allow-Headers"))
 	}
-	if rr.Header().Get("Access-Control-Expose-Headers") != "Authorization" {
-		t.Errorf("Expected Access-Control-Expose-Headers header to be 'Authorization', got '%s'", rr.Header().Get("Access-Control-Expose-Headers"))
+	exposed := "Authorization, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, X-Request-ID"
+	if rr.Header().Get("Access-Control-Expose-Headers") != exposed {
+		t.Errorf("Expected Access-Control-Expose-Headers header to be '%s', got '%s'", exposed, rr.Header().Get("Access-Control-Expose-Headers"))
 	}
 	if rr.Header().Get("Access-Control-Max-Age") != oneDay {
 		t.Errorf("Expected Access-Control-Max-Age header to be '%s', got '%s'", oneDay, rr.Header().Get("Access-Control-Max-Age"))
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"messaging-application/servers/gateway/logging"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/resetcodes"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+)
+
+// APIError is an error reported to clients with an HTTP status and a
+// stable, machine-readable code that does not change with the message.
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func newAPIError(status int, code string, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+var (
+	errMethodNotAllowed     = newAPIError(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	errUnsupportedMediaType = newAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+	errInvalidPayload       = newAPIError(http.StatusBadRequest, "invalid_payload", "Invalid request payload")
+	errInvalidAuthHeader    = newAPIError(http.StatusUnauthorized, "invalid_authorization", "Invalid authorization header")
+	errInvalidCredentials   = newAPIError(http.StatusUnauthorized, "invalid_credentials", "Invalid credentials")
+	errTooManyRequests      = newAPIError(http.StatusTooManyRequests, "too_many_requests", "Too many requests, try again later")
+	errBadGateway           = newAPIError(http.StatusBadGateway, "bad_gateway", "Bad gateway")
+	errInternal             = newAPIError(http.StatusInternalServerError, "internal_error", "Internal server error")
+)
+
+// toAPIError maps errors from the stores and session package to the
+// status and code clients see. Unrecognized errors become a generic 500
+// so internal details are not leaked.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, users.ErrUserNotFound):
+		return newAPIError(http.StatusNotFound, "user_not_found", err.Error())
+	case errors.Is(err, users.ErrDuplicate):
+		return newAPIError(http.StatusConflict, "duplicate_user", err.Error())
+	case errors.Is(err, users.ErrInvalidToken):
+		return newAPIError(http.StatusBadRequest, "invalid_token", err.Error())
+	case errors.Is(err, resetcodes.ErrInvalidCode):
+		return newAPIError(http.StatusBadRequest, "invalid_code", err.Error())
+	case errors.Is(err, sessions.ErrStateNotFound), errors.Is(err, sessions.ErrInvalidID):
+		return newAPIError(http.StatusUnauthorized, "invalid_session", err.Error())
+	default:
+		return errInternal
+	}
+}
+
+// writeError responds with the JSON body for err. Every handler reports
+// errors through here so clients can rely on one error format. Internal
+// errors are logged with the ID of the request they failed.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr == errInternal {
+		logging.Logger(r.Context()).Error("internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(apiErr)
+}
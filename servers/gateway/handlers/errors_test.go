@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"messaging-application/servers/gateway/logging"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{errMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+		{errBadGateway, http.StatusBadGateway, "bad_gateway"},
+		{users.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+		{fmt.Errorf("%w with that email", users.ErrDuplicate), http.StatusConflict, "duplicate_user"},
+		{sessions.ErrStateNotFound, http.StatusUnauthorized, "invalid_session"},
+		{fmt.Errorf("%w: bad signature", sessions.ErrInvalidID), http.StatusUnauthorized, "invalid_session"},
+		{errors.New("dial tcp: connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	// Setting the default logger redirects the log package too, so both
+	// are put back afterwards
+	defer func(logger *slog.Logger, w io.Writer, flags int) {
+		slog.SetDefault(logger)
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}(slog.Default(), log.Writer(), log.Flags())
+	logs := &bytes.Buffer{}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	req = req.WithContext(logging.NewContext(req.Context(), &logging.Request{ID: "request-1"}))
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		writeError(rr, req, c.err)
+
+		if status := rr.Code; status != c.status {
+			t.Errorf("%v: expected status %d, got %d", c.err, c.status, status)
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%v: expected JSON content type, got %s", c.err, contentType)
+		}
+
+		body := &APIError{}
+		err := json.Unmarshal(rr.Body.Bytes(), body)
+		if err != nil {
+			t.Fatalf("%v: expected JSON body, got %s", c.err, rr.Body.String())
+		}
+		if body.Code != c.code {
+			t.Errorf("%v: expected code %s, got %s", c.err, c.code, body.Code)
+		}
+		if c.status == http.StatusInternalServerError && body.Message != "Internal server error" {
+			t.Errorf("expected internal error details to be hidden, got %s", body.Message)
+		}
+	}
+
+	// only the internal error is logged, with the request it failed
+	line := struct {
+		Msg       string `json:"msg"`
+		RequestID string `json:"requestId"`
+		Error     string `json:"error"`
+	}{}
+	err := json.Unmarshal(logs.Bytes(), &line)
+	if err != nil {
+		t.Fatalf("expected a single log line, got %s", logs.String())
+	}
+	if line.Msg != "internal error" || line.RequestID != "request-1" || line.Error != "dial tcp: connection refused" {
+		t.Errorf("unexpected log line %s", logs.String())
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds how long the readiness checks may take together.
+const readyTimeout = 2 * time.Second
+
+// HealthzHandler reports that the process is alive.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// ReadyzHandler reports whether the gateway can serve traffic. It runs
+// every check and responds 503 if any of them fails.
+type ReadyzHandler struct {
+	checks map[string]func(context.Context) error
+
+	// Public leaves out which checks failed and why, answering only ok or
+	// fail, for a handler served where anyone can reach it.
+	Public bool
+}
+
+// NewReadyzHandler returns a handler that runs checks, keyed by the name
+// of the dependency they check.
+func NewReadyzHandler(checks map[string]func(context.Context) error) *ReadyzHandler {
+	return &ReadyzHandler{checks: checks}
+}
+
+func (rh *ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := map[string]string{}
+	for name, check := range rh.checks {
+		if err := check(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			results[name] = err.Error()
+		} else {
+			results[name] = "ok"
+		}
+	}
+
+	if rh.Public {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte("ok"))
+		} else {
+			w.Write([]byte("fail"))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	HealthzHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	redisUp := true
+	handler := NewReadyzHandler(map[string]func(context.Context) error{
+		"mysql": func(context.Context) error { return nil },
+		"redis": func(context.Context) error {
+			if !redisUp {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	})
+
+	expected := map[bool]int{true: http.StatusOK, false: http.StatusServiceUnavailable}
+	for _, up := range []bool{true, false} {
+		redisUp = up
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rr.Code != expected[up] {
+			t.Errorf("Expected status %d, got %d", expected[up], rr.Code)
+		}
+
+		results := map[string]string{}
+		err := json.NewDecoder(rr.Body).Decode(&results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results["mysql"] != "ok" {
+			t.Errorf("Expected mysql to be ok, got %q", results["mysql"])
+		}
+		if up && results["redis"] != "ok" || !up && results["redis"] != "connection refused" {
+			t.Errorf("Unexpected redis result %q", results["redis"])
+		}
+	}
+}
+
+func TestPublicReadyzHandler(t *testing.T) {
+	redisUp := true
+	handler := NewReadyzHandler(map[string]func(context.Context) error{
+		"mysql": func(context.Context) error { return nil },
+		"redis": func(context.Context) error {
+			if !redisUp {
+				return errors.New("dial tcp 10.0.0.5:6379: connection refused")
+			}
+			return nil
+		},
+	})
+	handler.Public = true
+
+	expected := map[bool]struct {
+		status int
+		body   string
+	}{
+		true:  {http.StatusOK, "ok"},
+		false: {http.StatusServiceUnavailable, "fail"},
+	}
+	for _, up := range []bool{true, false} {
+		redisUp = up
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rr.Code != expected[up].status {
+			t.Errorf("Expected status %d, got %d", expected[up].status, rr.Code)
+		}
+		if rr.Body.String() != expected[up].body {
+			t.Errorf("Expected body %q, got %q", expected[up].body, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"messaging-application/servers/gateway/logging"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// requestIDPattern limits the request IDs accepted from clients so they
+// cannot inject arbitrary text into the logs.
+var requestIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+// statusRecorder captures the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	sr.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// LoggingHandler assigns each request an ID, or keeps the one in its
+// X-Request-ID header, and logs one line per request once it is served.
+type LoggingHandler struct {
+	handler http.Handler
+	logger  *slog.Logger
+}
+
+func NewLoggingHandler(handlerToWrap http.Handler, logger *slog.Logger) *LoggingHandler {
+	return &LoggingHandler{handler: handlerToWrap, logger: logger}
+}
+
+func (lh *LoggingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	id := r.Header.Get("X-Request-ID")
+	if !requestIDPattern.MatchString(id) {
+		id = logging.NewRequestID()
+	}
+	// Set on the request too so the proxy passes it on to services
+	r.Header.Set("X-Request-ID", id)
+	w.Header().Set("X-Request-ID", id)
+
+	req := &logging.Request{ID: id}
+	r = r.WithContext(logging.NewContext(r.Context(), req))
+	recorder := &statusRecorder{ResponseWriter: w}
+	lh.handler.ServeHTTP(recorder, r)
+
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	attrs := []slog.Attr{
+		slog.String("requestId", id),
+		slog.String("method", r.Method),
+		slog.String("pattern", r.Pattern),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", recorder.status),
+		slog.Int("bytes", recorder.bytes),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if req.UserID != 0 {
+		attrs = append(attrs, slog.Int("userId", req.UserID))
+	}
+	lh.logger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+}
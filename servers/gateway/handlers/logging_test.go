@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"messaging-application/servers/gateway/logging"
+	"messaging-application/servers/gateway/models/users"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type logLine struct {
+	RequestID string `json:"requestId"`
+	Method    string `json:"method"`
+	Pattern   string `json:"pattern"`
+	Status    int    `json:"status"`
+	Bytes     int    `json:"bytes"`
+	UserID    int    `json:"userId"`
+}
+
+func TestLoggingHandler(t *testing.T) {
+	logs := &bytes.Buffer{}
+	handler := NewLoggingHandler(newContext(), slog.New(slog.NewJSONHandler(logs, nil)))
+
+	// create new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+	if len(rr.Header().Get("X-Request-ID")) != 32 {
+		t.Errorf("Expected a generated request ID, got %q", rr.Header().Get("X-Request-ID"))
+	}
+
+	authHeader := rr.Header().Get("Authorization")
+
+	// retrieve the user, passing a request ID
+	logs.Reset()
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("X-Request-ID", "client-id-1")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Request-ID") != "client-id-1" {
+		t.Errorf("Expected the client's request ID to be kept, got %q", rr.Header().Get("X-Request-ID"))
+	}
+
+	line := &logLine{}
+	err = json.Unmarshal(logs.Bytes(), line)
+	if err != nil {
+		t.Fatalf("Expected one JSON log line, got %q: %s", logs.String(), err)
+	}
+	expected := logLine{
+		RequestID: "client-id-1",
+		Method:    http.MethodGet,
+		Pattern:   "/v1/users/{UserID}",
+		Status:    http.StatusOK,
+		Bytes:     rr.Body.Len(),
+		UserID:    1,
+	}
+	if *line != expected {
+		t.Errorf("Expected log line %+v, got %+v", expected, *line)
+	}
+
+	// invalid request IDs are replaced
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if id := rr.Header().Get("X-Request-ID"); id == "bad id\nwith newline" || len(id) != 32 {
+		t.Errorf("Expected an invalid request ID to be replaced, got %q", id)
+	}
+}
+
+func TestRequestIDInContext(t *testing.T) {
+	var seen string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	})
+	handler := NewLoggingHandler(inner, slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if seen != "abc" {
+		t.Errorf("Expected request ID abc in the handler's context, got %q", seen)
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"messaging-application/servers/gateway/metrics"
+	"net/http"
+	"time"
+)
+
+// MetricsHandler records the count and latency of requests by the route
+// pattern they matched and their status.
+type MetricsHandler struct {
+	handler http.Handler
+	metrics *metrics.Metrics
+}
+
+func NewMetricsHandler(handlerToWrap http.Handler, m *metrics.Metrics) *MetricsHandler {
+	return &MetricsHandler{handler: handlerToWrap, metrics: m}
+}
+
+func (mh *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w}
+	mh.handler.ServeHTTP(recorder, r)
+
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	mh.metrics.ObserveRequest(r.Pattern, recorder.status, time.Since(start))
+}
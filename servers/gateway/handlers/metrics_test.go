@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"io"
+	"messaging-application/servers/gateway/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	m := metrics.NewMetrics()
+	handler := NewMetricsHandler(newContext(), m)
+
+	for _, path := range []string{"/v1/users/me", "/v1/users/2", "/nowhere"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+	}
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, series := range []string{
+		`gateway_http_requests_total{pattern="/v1/users/{UserID}",status="401"} 2`,
+		`gateway_http_requests_total{pattern="none",status="404"} 1`,
+	} {
+		if !strings.Contains(string(body), series) {
+			t.Errorf("Expected metrics to contain %s", series)
+		}
+	}
+}
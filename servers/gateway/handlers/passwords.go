@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/resetcodes"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// allow reports whether the request is within the reset limiter's limits
+// for each of keys. If it is not, it writes a 429 response.
+func (ctx *HandlerContext) allow(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	for _, key := range keys {
+		res, err := ctx.ResetLimiter.Allow(r.Context(), key)
+		if err != nil {
+			writeError(w, r, err)
+			return false
+		}
+		if !res.Allowed {
+			writeTooManyRequests(w, r, res.RetryAfter)
+			return false
+		}
+	}
+	return true
+}
+
+func writeTooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", seconds(retryAfter))
+	writeError(w, r, errTooManyRequests)
+}
+
+// ResetCodesHandler emails a one-time password reset code. It responds the
+// same way whether or not an account exists for the email, so it cannot be
+// used to find out which addresses are registered.
+func (ctx *HandlerContext) ResetCodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	request := &struct {
+		Email string `json:"email"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Email == "" {
+		writeError(w, r, errInvalidPayload)
+		return
+	}
+
+	email := users.NormalizeEmail(request.Email)
+	if !ctx.allow(w, r, "ip:"+ctx.clientIP(r), "email:"+email) {
+		return
+	}
+
+	user, err := ctx.UserStore.GetByEmail(r.Context(), email)
+	if err == nil {
+		code, err := resetcodes.Issue(r.Context(), user.Email, ctx.Keys.Secret(resetcodes.KeyPurpose), ctx.ResetCodes)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		body := fmt.Sprintf("Hi %s,\n\nYour password reset code is %s. It can only be used once.\n\n"+
+			"If you did not ask to reset your password, ignore this message.", user.FullName(), code)
+		err = ctx.Mailer.Send(user.Email, "Reset your password", body)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	} else if !errors.Is(err, users.ErrUserNotFound) {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("If an account exists for that email, a reset code has been sent"))
+}
+
+// PasswordsHandler sets a new password for the account with the email in
+// the path when given a valid reset code. Every session of the account is
+// signed out afterwards.
+func (ctx *HandlerContext) PasswordsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	email := users.NormalizeEmail(r.PathValue("Email"))
+	if !ctx.allow(w, r, "ip:"+ctx.clientIP(r), "email:"+email) {
+		return
+	}
+
+	reset := &users.PasswordReset{}
+	err := json.NewDecoder(r.Body).Decode(reset)
+	if err != nil {
+		writeError(w, r, errInvalidPayload)
+		return
+	}
+
+	err = reset.Validate()
+	if err != nil {
+		writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_password", err.Error()))
+		return
+	}
+
+	// Look the user up first, so that a failed lookup never uses up the
+	// code
+	user, err := ctx.UserStore.GetByEmail(r.Context(), email)
+	if errors.Is(err, users.ErrUserNotFound) {
+		writeError(w, r, resetcodes.ErrInvalidCode)
+		return
+	} else if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = resetcodes.Redeem(r.Context(), email, reset.Code, ctx.Keys.Secrets(resetcodes.KeyPurpose), ctx.ResetCodes)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = user.SetPassword(reset.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = ctx.UserStore.Update(r.Context(), user.ID, user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The password has already been reset, so sign out every device even
+	// if the client hangs up
+	err = sessions.EndAllSessions(context.WithoutCancel(r.Context()), user.ID, ctx.SessionStore)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
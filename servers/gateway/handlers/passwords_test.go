@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"messaging-application/servers/gateway/models/users"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var resetCodePattern = regexp.MustCompile(`reset code is (\d+)`)
+
+func TestPasswordReset(t *testing.T) {
+	handler := newContext()
+
+	// create new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	authHeader := rr.Header().Get("Authorization")
+
+	// request codes for an unknown and a known email
+	for _, email := range []string{"unknown@example.com", "valid_email@example.com"} {
+		req, err = http.NewRequest(http.MethodPost, "/v1/resetcodes", bytes.NewReader([]byte(`{"email":"`+email+`"}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("Expected status %d, got %d", http.StatusAccepted, status)
+		}
+	}
+
+	match := resetCodePattern.FindStringSubmatch(mailbox.String())
+	if match == nil {
+		t.Fatalf("Expected a reset code to be mailed, got: %s", mailbox.String())
+	}
+	code := match[1]
+
+	// reset with a wrong code, a weak password and then the mailed code
+	resets := []*users.PasswordReset{
+		{Code: "000000x", Password: "newpassword343", PasswordConf: "newpassword343"},
+		{Code: code, Password: "short", PasswordConf: "short"},
+		{Code: code, Password: "newpassword343", PasswordConf: "newpassword343"},
+	}
+	expected := []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusNoContent}
+	for i, reset := range resets {
+		jsonData, err = json.Marshal(reset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err = http.NewRequest(http.MethodPut, "/v1/passwords/valid_email@example.com", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != expected[i] {
+			t.Errorf("Expected status %d, got %d", expected[i], status)
+		}
+	}
+
+	// the old session is revoked
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	// sign in with the new password
+	jsonData, err = json.Marshal(&users.Credentials{
+		Password: "newpassword343",
+		Email:    "valid_email@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+}
+
+func TestResetCodesRateLimit(t *testing.T) {
+	handler := newContext()
+
+	for i := 0; i < 6; i++ {
+		req, err := http.NewRequest(http.MethodPost, "/v1/resetcodes", bytes.NewReader([]byte(`{"email":"someone@example.com"}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if i < 5 && rr.Code != http.StatusAccepted {
+			t.Errorf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+		}
+		if i == 5 {
+			if rr.Code != http.StatusTooManyRequests {
+				t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+			}
+			if rr.Header().Get("Retry-After") == "" {
+				t.Error("Expected Retry-After header to be set")
+			}
+		}
+	}
+}
+
+func TestPasswordResetEmailCase(t *testing.T) {
+	handler := newContext()
+
+	// create a user with a mixed case email
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "Valid_Email@Example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	// request a code and reset the password with the email in other cases
+	req, err = http.NewRequest(http.MethodPost, "/v1/resetcodes", bytes.NewReader([]byte(`{"email":"VALID_EMAIL@example.com"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	match := resetCodePattern.FindStringSubmatch(mailbox.String())
+	if match == nil {
+		t.Fatalf("Expected a reset code to be mailed, got: %s", mailbox.String())
+	}
+
+	jsonData, err = json.Marshal(&users.PasswordReset{Code: match[1], Password: "newpassword343", PasswordConf: "newpassword343"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPut, "/v1/passwords/valid_email@EXAMPLE.com", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, status, rr.Body.String())
+	}
+
+	// sign in with the new password, in yet another case
+	jsonData, err = json.Marshal(&users.Credentials{
+		Password: "newpassword343",
+		Email:    " valid_email@example.COM",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"messaging-application/servers/gateway/logging"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// ServiceProxy returns a handler that forwards requests from signed-in
+// users to the service instances listed in addrs, a comma-separated list of
+// host:port pairs used round-robin. The user's profile is passed as JSON
+// in the X-User header so services never need to read the session store.
+func (ctx *HandlerContext) ServiceProxy(addrs string) (http.Handler, error) {
+	targets, err := parseServiceAddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var next atomic.Uint64
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			target := targets[(next.Add(1)-1)%uint64(len(targets))]
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logging.Logger(r.Context()).Error("error proxying request", "path", r.URL.Path, "error", err)
+			writeError(w, r, errBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never trust an identity asserted by the client itself
+		r.Header.Del("X-User")
+
+		sessionState, err := ctx.getSessionState(r)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		user, err := json.Marshal(sessionState.User)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		r.Header.Set("X-User", string(user))
+		proxy.ServeHTTP(w, r)
+	}), nil
+}
+
+func parseServiceAddrs(addrs string) ([]*url.URL, error) {
+	targets := []*url.URL{}
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		target, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service address %s: %w", addr, err)
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no service addresses given")
+	}
+	return targets, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"messaging-application/servers/gateway/models/users"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServiceProxy(t *testing.T) {
+	handler := newContext()
+
+	var forwardedUser string
+	hits := []int{0, 0}
+	newService := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwardedUser = r.Header.Get("X-User")
+			hits[i]++
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(r.URL.Path))
+		}))
+	}
+	first, second := newService(0), newService(1)
+	defer first.Close()
+	defer second.Close()
+
+	addrs := strings.TrimPrefix(first.URL, "http://") + ", " + second.URL
+	proxy, err := ctx.ServiceProxy(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+	authHeader := rr.Header().Get("Authorization")
+
+	// Unauthenticated requests are rejected before reaching a service
+	req = httptest.NewRequest(http.MethodGet, "/v1/channels", nil)
+	req.Header.Set("X-User", `{"id":2}`)
+	rr = httptest.NewRecorder()
+	proxy.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+	if hits[0]+hits[1] != 0 {
+		t.Error("Expected unauthenticated request not to be forwarded")
+	}
+
+	// Authenticated requests are spread across services with the user in X-User
+	for i := 0; i < 4; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/v1/channels", nil)
+		req.Header.Set("Authorization", authHeader)
+		req.Header.Set("X-User", `{"id":2}`)
+		rr = httptest.NewRecorder()
+		proxy.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if rr.Body.String() != "/v1/channels" {
+			t.Errorf("Expected path /v1/channels to be forwarded, got %s", rr.Body.String())
+		}
+
+		user := &users.User{}
+		err = json.Unmarshal([]byte(forwardedUser), user)
+		if err != nil {
+			t.Fatalf("Expected X-User to be valid JSON, got %s", forwardedUser)
+		}
+		if user.ID != 1 || user.FirstName != "Jon" {
+			t.Errorf("Expected X-User to describe user 1, got %s", forwardedUser)
+		}
+	}
+
+	if hits[0] != 2 || hits[1] != 2 {
+		t.Errorf("Expected requests to alternate between services, got %v", hits)
+	}
+}
+
+func TestServiceProxyAddrs(t *testing.T) {
+	newContext()
+
+	for _, addrs := range []string{"", " , "} {
+		_, err := ctx.ServiceProxy(addrs)
+		if err == nil {
+			t.Errorf("Expected an error for addresses `%s`", addrs)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"math"
+	"messaging-application/servers/gateway/ratelimit"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteLimit applies Limiter to every request whose path starts with
+// Prefix. The longest matching prefix wins.
+type RouteLimit struct {
+	Prefix  string
+	Limiter ratelimit.Limiter
+}
+
+// RateLimitHandler rate limits each signed-in user, or each client IP for
+// anonymous requests, separately on every route.
+type RateLimitHandler struct {
+	handler http.Handler
+	ctx     *HandlerContext
+	routes  []RouteLimit
+}
+
+func (ctx *HandlerContext) NewRateLimitHandler(handlerToWrap http.Handler, routes []RouteLimit) *RateLimitHandler {
+	return &RateLimitHandler{handler: handlerToWrap, ctx: ctx, routes: routes}
+}
+
+func (rl *RateLimitHandler) route(path string) *RouteLimit {
+	var match *RouteLimit
+	for i, route := range rl.routes {
+		if strings.HasPrefix(path, route.Prefix) && (match == nil || len(route.Prefix) > len(match.Prefix)) {
+			match = &rl.routes[i]
+		}
+	}
+	return match
+}
+
+// clientKey identifies who is making the request. Anonymous clients are
+// told apart by clientIP, so they cannot dodge their limit by sending a
+// different X-Forwarded-For with each request.
+func (rl *RateLimitHandler) clientKey(r *http.Request) string {
+	sessionState, err := rl.ctx.getSessionState(r)
+	if err == nil {
+		return "user:" + strconv.Itoa(sessionState.User.ID)
+	}
+	return "ip:" + rl.ctx.clientIP(r)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// setRateLimitHeaders sets the RateLimit-* headers from the IETF
+// RateLimit header fields draft.
+func setRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+}
+
+func (rl *RateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := rl.route(r.URL.Path)
+	if route == nil || r.Method == http.MethodOptions {
+		rl.handler.ServeHTTP(w, r)
+		return
+	}
+
+	res, err := route.Limiter.Allow(r.Context(), rl.clientKey(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setRateLimitHeaders(w, res)
+	if !res.Allowed {
+		writeTooManyRequests(w, r, res.RetryAfter)
+		return
+	}
+
+	rl.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	mux := newContext()
+	handler := ctx.NewRateLimitHandler(mux, []RouteLimit{
+		{Prefix: "/", Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Limit: 100, Period: time.Minute})},
+		{Prefix: "/v1/users/", Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{Limit: 2, Period: time.Minute})},
+	})
+
+	// create new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+	if rr.Header().Get("RateLimit-Limit") != "100" {
+		t.Errorf("Expected the default limit of 100, got %q", rr.Header().Get("RateLimit-Limit"))
+	}
+
+	authHeader := rr.Header().Get("Authorization")
+
+	// the signed-in user gets two requests to /v1/users/
+	expected := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	remaining := []string{"1", "0", "0"}
+	for i, status := range expected {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authHeader)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != status {
+			t.Errorf("Expected status %d, got %d", status, rr.Code)
+		}
+		if rr.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Expected RateLimit-Limit of 2, got %q", rr.Header().Get("RateLimit-Limit"))
+		}
+		if rr.Header().Get("RateLimit-Remaining") != remaining[i] {
+			t.Errorf("Expected RateLimit-Remaining of %s, got %q", remaining[i], rr.Header().Get("RateLimit-Remaining"))
+		}
+	}
+	if rr.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After of 30 seconds, got %q", rr.Header().Get("Retry-After"))
+	}
+	if rr.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("Expected RateLimit-Reset of 60 seconds, got %q", rr.Header().Get("RateLimit-Reset"))
+	}
+
+	// anonymous clients are limited by IP, separately from the user
+	req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	// a client cannot get a fresh limit by making up X-Forwarded-For
+	expected = []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range expected {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != status {
+			t.Errorf("Expected status %d, got %d", status, rr.Code)
+		}
+	}
+
+	// but clients behind a trusted proxy are limited separately
+	for i := range 3 {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = "10.0.0.2:4000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+	}
+}
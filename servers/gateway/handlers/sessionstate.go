@@ -2,19 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"messaging-application/servers/gateway/logging"
 	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"strings"
 	"time"
 )
 
 type SessionState struct {
-	Start time.Time  `json:"start"`
-	User  users.User `json:"user"`
+	Start     time.Time  `json:"start"`
+	User      users.User `json:"user"`
+	UserAgent string     `json:"userAgent"`
+	IP        string     `json:"ip"`
 }
 
-func GetSerializedSessionState(user *users.User) (string, error) {
+// GetSerializedSessionState returns the state of a new session for the
+// user, recording the device it was started from.
+func (ctx *HandlerContext) GetSerializedSessionState(user *users.User, r *http.Request) (string, error) {
 	state := SessionState{
-		Start: time.Now(),
-		User:  *user,
+		Start:     time.Now(),
+		User:      *user,
+		UserAgent: r.UserAgent(),
+		IP:        ctx.clientIP(r),
 	}
 
 	serialized, err := json.Marshal(state)
@@ -24,3 +35,92 @@ func GetSerializedSessionState(user *users.User) (string, error) {
 
 	return string(serialized), nil
 }
+
+// getSessionState returns the state of the session whose token is in the
+// request's Authorization header.
+func (ctx *HandlerContext) getSessionState(r *http.Request) (*SessionState, error) {
+	sessionToken, err := getSessionToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	serializedSessionState, err := sessions.GetSessionState(r.Context(), sessionToken, ctx.Keys, ctx.SessionStore)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionState, err := parseSessionState(serializedSessionState)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.SetUserID(r.Context(), sessionState.User.ID)
+	return sessionState, nil
+}
+
+// getSessionID returns the ID of the session whose token is in the
+// request's Authorization header.
+func (ctx *HandlerContext) getSessionID(r *http.Request) (string, error) {
+	sessionToken, err := getSessionToken(r)
+	if err != nil {
+		return "", err
+	}
+
+	return sessions.GetSessionID(sessionToken, ctx.Keys)
+}
+
+// refreshSessions replaces the cached user in each of the user's live
+// sessions so they never serve a stale profile. Sessions that end while
+// they are being refreshed stay ended.
+func (ctx *HandlerContext) refreshSessions(r *http.Request, user *users.User) error {
+	sessionIDs, err := ctx.SessionStore.GetSessionIDs(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		serialized, err := ctx.SessionStore.Get(r.Context(), sessionID)
+		if errors.Is(err, sessions.ErrStateNotFound) {
+			ctx.SessionStore.RemoveSessionID(r.Context(), user.ID, sessionID)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		sessionState, err := parseSessionState(serialized)
+		if err != nil {
+			return err
+		}
+		sessionState.User = *user
+
+		updated, err := json.Marshal(sessionState)
+		if err != nil {
+			return err
+		}
+		err = ctx.SessionStore.Replace(r.Context(), sessionID, string(updated))
+		if errors.Is(err, sessions.ErrStateNotFound) {
+			ctx.SessionStore.RemoveSessionID(r.Context(), user.ID, sessionID)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getSessionToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", errInvalidAuthHeader
+	}
+	return authHeader[7:], nil
+}
+
+func parseSessionState(serialized string) (*SessionState, error) {
+	sessionState := &SessionState{}
+	err := json.Unmarshal([]byte(serialized), sessionState)
+	if err != nil {
+		return nil, err
+	}
+	return sessionState, nil
+}
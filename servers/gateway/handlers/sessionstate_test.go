@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"testing"
+)
+
+// revokingStore ends each session right after it is read, as if it were
+// revoked while it was being refreshed.
+type revokingStore struct {
+	sessions.Store
+}
+
+func (s revokingStore) Get(ctx context.Context, key string) (string, error) {
+	state, err := s.Store.Get(ctx, key)
+	if err == nil {
+		s.Store.Delete(ctx, key)
+	}
+	return state, err
+}
+
+func TestRefreshSessionsKeepsRevokedSessionsEnded(t *testing.T) {
+	newContext()
+	store := sessions.NewMemoryStore("1h")
+	ctx.SessionStore = revokingStore{store}
+
+	user := &users.User{ID: 1, FirstName: "Jon"}
+	err := store.Set(context.Background(), "revoked", `{"user":{"id":1}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.AddSessionID(context.Background(), user.ID, "revoked")
+
+	req, err := http.NewRequest(http.MethodPatch, "/v1/users/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.refreshSessions(req, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Get(context.Background(), "revoked")
+	if !errors.Is(err, sessions.ErrStateNotFound) {
+		t.Errorf("Expected the revoked session to stay ended but got %v", err)
+	}
+	ids, _ := store.GetSessionIDs(context.Background(), user.ID)
+	if len(ids) != 0 {
+		t.Errorf("Expected the revoked session to be unindexed but got %v", ids)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"messaging-application/servers/gateway/logging"
+	"messaging-application/servers/gateway/models/signins"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultSignInsLimit = 20
+const maxSignInsLimit = 100
+
+// maxUserAgentLength matches the user_agent column of user_signins.
+const maxUserAgentLength = 512
+
+// signInIP returns the client IP to record for r. clientIP already falls
+// back to the connection's address when X-Forwarded-For cannot be
+// believed, but that address is left out too if it is not an IP, since it
+// would not fit the ip column of user_signins.
+func (ctx *HandlerContext) signInIP(r *http.Request) string {
+	ip := ctx.clientIP(r)
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// recordSignIn adds an attempt to sign in to the audit log. userID is zero
+// when the email did not match an account. A failure to record the attempt
+// is logged rather than failing the sign-in.
+func (ctx *HandlerContext) recordSignIn(r *http.Request, userID int, success bool) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	// Record the attempt even if the client hangs up, so that attempts
+	// cannot be kept out of the log by disconnecting early
+	_, err := ctx.SignInStore.Insert(context.WithoutCancel(r.Context()), &signins.SignIn{
+		UserID:    userID,
+		Time:      time.Now().UTC(),
+		IP:        ctx.signInIP(r),
+		UserAgent: userAgent,
+		Success:   success,
+	})
+	if err != nil {
+		logging.Logger(r.Context()).Error("error recording sign-in", "userId", userID, "error", err)
+	}
+}
+
+// SignInsHandler responds with the signed-in user's sign-in attempts,
+// newest first. Pages are requested with the before and limit query string
+// parameters, where before is the ID of the last sign-in already seen.
+func (ctx *HandlerContext) SignInsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+
+	sessionState, err := ctx.getSessionState(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var before int64
+	if param := query.Get("before"); param != "" {
+		before, err = strconv.ParseInt(param, 10, 64)
+		if err != nil || before < 1 {
+			writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_query", "before must be a positive sign-in ID"))
+			return
+		}
+	}
+
+	limit := defaultSignInsLimit
+	if param := query.Get("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxSignInsLimit {
+			writeError(w, r, newAPIError(http.StatusBadRequest, "invalid_query", "limit must be between 1 and 100"))
+			return
+		}
+	}
+
+	found, err := ctx.SignInStore.GetByUserID(r.Context(), sessionState.User.ID, before, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(found)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"messaging-application/servers/gateway/models/signins"
+	"messaging-application/servers/gateway/models/users"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignIns(t *testing.T) {
+	handler := newContext()
+
+	// create new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+
+	authHeader := rr.Header().Get("Authorization")
+
+	// sign in with a wrong password, an unknown email and then correctly
+	attempts := []*users.Credentials{
+		{Email: "valid_email@example.com", Password: "wrong_password"},
+		{Email: "unknown@example.com", Password: "password343"},
+		{Email: "valid_email@example.com", Password: "password343"},
+	}
+	for _, credentials := range attempts {
+		jsonData, err = json.Marshal(credentials)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err = http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "phone")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.RemoteAddr = "10.0.0.2:4000"
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+	}
+
+	// page through my sign-ins
+	pages := [][]bool{{true}, {false}, {}}
+	before := ""
+	for i, expected := range pages {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me/signins?limit=1"+before, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authHeader)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+
+		page := []*signins.SignIn{}
+		err = json.NewDecoder(rr.Body).Decode(&page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != len(expected) {
+			t.Fatalf("Expected %d sign-ins on page %d, got %d", len(expected), i+1, len(page))
+		}
+		for j, signIn := range page {
+			if signIn.Success != expected[j] {
+				t.Errorf("Expected success to be %t on page %d", expected[j], i+1)
+			}
+			if signIn.IP != "203.0.113.7" || signIn.UserAgent != "phone" {
+				t.Errorf("Expected sign-in from phone at 203.0.113.7, got %s at %s", signIn.UserAgent, signIn.IP)
+			}
+			before = fmt.Sprintf("&before=%d", signIn.ID)
+		}
+	}
+
+	// invalid pagination
+	for _, query := range []string{"limit=0", "limit=101", "before=abc"} {
+		req, err = http.NewRequest(http.MethodGet, "/v1/users/me/signins?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authHeader)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, status)
+		}
+	}
+}
+
+// failingSignInStore fails to record any sign-in.
+type failingSignInStore struct {
+	signins.Store
+}
+
+func (s failingSignInStore) Insert(ctx context.Context, signIn *signins.SignIn) (*signins.SignIn, error) {
+	return nil, errors.New("audit log unavailable")
+}
+
+func TestSignInAudit(t *testing.T) {
+	handler := newContext()
+	signInStore := ctx.SignInStore
+
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatal(rr.Body.String())
+	}
+	userID := 1
+
+	signIn := func(password string, remoteAddr string) int {
+		jsonData, err := json.Marshal(&users.Credentials{Email: "valid_email@example.com", Password: password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// an address that is not an IP is left out of the audit log
+	if status := signIn("password343", "pipe"); status != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+	found, err := signInStore.GetByUserID(context.Background(), userID, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].IP != "" {
+		t.Errorf("Expected a sign-in without an IP, got %v", found)
+	}
+
+	// signing in still works when the attempt cannot be recorded
+	ctx.SignInStore = failingSignInStore{}
+	if status := signIn("password343", "203.0.113.7:4000"); status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	// and failures still count towards a lockout
+	for range 5 {
+		signIn("wrong_password", "203.0.113.7:4000")
+	}
+	if status := signIn("password343", "203.0.113.7:4000"); status != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, status)
+	}
+}
@@ -43,16 +43,24 @@ type Metadata struct {
 	Videos      []*PreviewVideo `json:"videos"`
 }
 
+// errFetchFailed is wrapped by errors fetching the page to summarize, which
+// are the client's fault rather than the gateway's.
+var errFetchFailed = errors.New("error fetching html")
+
+// SummaryClient fetches the pages that are summarized. Its transport can
+// be replaced to instrument the fetches.
+var SummaryClient = &http.Client{}
+
 func SummaryHandler(w http.ResponseWriter, r *http.Request) {
 	url := r.URL.Query().Get("url")
 	metadata, err := fetchHTML(url)
 	if err != nil {
-		if strings.HasPrefix(err.Error(), "error fetching html:") {
-			w.WriteHeader(http.StatusBadRequest)
+		if errors.Is(err, errFetchFailed) {
+			writeError(w, r, newAPIError(http.StatusBadRequest, "fetch_failed", err.Error()))
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, err)
 		}
-		w.Write([]byte(err.Error()))
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
@@ -60,10 +68,9 @@ func SummaryHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func fetchHTML(url string) (*Metadata, error) {
-	resp, err := http.Get(url)
+	resp, err := SummaryClient.Get(url)
 	if err != nil {
-		message := fmt.Sprintf("error fetching html: %v", err)
-		return nil, errors.New(message)
+		return nil, fmt.Errorf("%w: %v", errFetchFailed, err)
 	}
 	defer resp.Body.Close()
 	metadata := extractSummary(resp)
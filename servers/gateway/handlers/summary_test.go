@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -36,8 +37,8 @@ func TestFetchHTML(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error for invalid URL")
 		}
-		if !strings.HasPrefix(err.Error(), "error fetching html:") {
-			t.Errorf("expected error to start with 'error fetching html:', got '%s'", err.Error())
+		if !errors.Is(err, errFetchFailed) {
+			t.Errorf("expected errFetchFailed, got '%s'", err.Error())
 		}
 	})
 
@@ -47,8 +48,8 @@ func TestFetchHTML(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error for unreachable server")
 		}
-		if !strings.HasPrefix(err.Error(), "error fetching html:") {
-			t.Errorf("expected error to start with 'error fetching html:', got '%s'", err.Error())
+		if !errors.Is(err, errFetchFailed) {
+			t.Errorf("expected errFetchFailed, got '%s'", err.Error())
 		}
 	})
 }
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"messaging-application/servers/gateway/logging"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Sessions are carried in bearer tokens rather than cookies, so a
+	// cross-origin page cannot open a socket on a user's behalf.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WebSocketHandler upgrades the connection of a signed-in user so they
+// receive real-time notifications. Browsers cannot set headers on a
+// WebSocket handshake, so the session token may also be passed in the
+// auth query string parameter.
+func (ctx *HandlerContext) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	sessionToken := r.URL.Query().Get("auth")
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		sessionToken = authHeader[7:]
+	}
+	if sessionToken == "" {
+		writeError(w, r, errInvalidAuthHeader)
+		return
+	}
+
+	serializedSessionState, err := sessions.GetSessionState(r.Context(), sessionToken, ctx.Keys, ctx.SessionStore)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sessionState, err := parseSessionState(serializedSessionState)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	logging.SetUserID(r.Context(), sessionState.User.ID)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		return
+	}
+
+	ctx.Notifier.Register(sessionState.User.ID, conn)
+}
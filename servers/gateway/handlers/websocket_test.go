@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"messaging-application/servers/gateway/events"
+	"messaging-application/servers/gateway/models/users"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	server := httptest.NewServer(NewLoggingHandler(newContext(), slog.Default()))
+	defer server.Close()
+
+	// Create a new user
+	jsonData, err := json.Marshal(&users.NewUser{
+		Password:     "password343",
+		PasswordConf: "password343",
+		Email:        "valid_email@example.com",
+		Username:     "jondoe",
+		FirstName:    "Jon",
+		LastName:     "Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Post(server.URL+"/v1/users", "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	sessionToken := strings.TrimPrefix(resp.Header.Get("Authorization"), "Bearer ")
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/ws"
+
+	// Missing and invalid tokens are rejected
+	for _, target := range []string{wsURL, wsURL + "?auth=invalid"} {
+		_, resp, err := websocket.DefaultDialer.Dial(target, nil)
+		if err == nil {
+			t.Errorf("Expected dial to %s to fail", target)
+		} else if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+
+	// The token may be passed in the query string or the Authorization header
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+sessionToken)
+	fromHeader, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fromHeader.Close()
+
+	fromQuery, _, err := websocket.DefaultDialer.Dial(wsURL+"?auth="+url.QueryEscape(sessionToken), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fromQuery.Close()
+
+	for ctx.Notifier.Connections(1) != 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = ctx.Notifier.Notify(&events.Event{Type: "message-new", Payload: json.RawMessage(`{}`), UserIDs: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, conn := range []*websocket.Conn{fromHeader, fromQuery} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Expected event to be delivered: %s", err)
+		}
+		if !strings.Contains(string(message), `"type":"message-new"`) {
+			t.Errorf("Unexpected event %s", message)
+		}
+	}
+}
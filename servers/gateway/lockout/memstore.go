@@ -0,0 +1,122 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type counter struct {
+	failures int
+	expires  time.Time
+}
+
+// MemoryStore keeps counters in process memory, for tests and single
+// instance setups. Counters are not shared between gateway replicas.
+// Expired counters and locks are removed when they are next used, or by
+// Sweep.
+type MemoryStore struct {
+	mu       sync.Mutex
+	window   time.Duration
+	counters map[string]*counter
+	locks    map[string]time.Time
+	now      func() time.Time
+}
+
+// NewMemoryStore returns a store whose counters expire window after the
+// last failure.
+func NewMemoryStore(window time.Duration) *MemoryStore {
+	return &MemoryStore{
+		window:   window,
+		counters: map[string]*counter{},
+		locks:    map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+func (m *MemoryStore) Fail(ctx context.Context, key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.expires) {
+		c = &counter{}
+		m.counters[key] = c
+	}
+	c.failures++
+	c.expires = now.Add(m.window)
+	return c.failures, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locks[key] = m.now().Add(d)
+	return nil
+}
+
+func (m *MemoryStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	until, ok := m.locks[key]
+	if !ok {
+		return 0, nil
+	}
+	d := until.Sub(m.now())
+	if d <= 0 {
+		delete(m.locks, key)
+		return 0, nil
+	}
+	return d, nil
+}
+
+// Sweep removes expired counters and locks, so that keys that are never
+// used again do not stay in memory. It returns how many were removed.
+func (m *MemoryStore) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	swept := 0
+	now := m.now()
+	for key, c := range m.counters {
+		if !now.Before(c.expires) {
+			delete(m.counters, key)
+			swept++
+		}
+	}
+	for key, until := range m.locks {
+		if !now.Before(until) {
+			delete(m.locks, key)
+			swept++
+		}
+	}
+	return swept
+}
+
+// StartSweeper calls Sweep every interval until the returned function is
+// called.
+func (m *MemoryStore) StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.Sweep()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
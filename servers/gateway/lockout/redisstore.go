@@ -0,0 +1,81 @@
+package lockout
+
+import (
+	"context"
+	"messaging-application/servers/gateway/timeout"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps counters in Redis so that every gateway replica sees
+// the same failures and lockouts.
+type RedisStore struct {
+	rdb    *redis.Client
+	window time.Duration
+
+	// Timeout bounds each call to Redis. Zero leaves calls bounded only
+	// by their context.
+	Timeout time.Duration
+}
+
+// NewRedisStore returns a store whose counters expire window after the
+// last failure.
+func NewRedisStore(client *redis.Client, window time.Duration) *RedisStore {
+	return &RedisStore{
+		rdb:     client,
+		window:  window,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func failuresKey(key string) string {
+	return "lockout:failures:" + key
+}
+
+func lockKey(key string) string {
+	return "lockout:locked:" + key
+}
+
+func (rs *RedisStore) Fail(ctx context.Context, key string) (int, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	pipe := rs.rdb.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey(key))
+	pipe.Expire(ctx, failuresKey(key), rs.window)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(failures.Val()), nil
+}
+
+func (rs *RedisStore) Reset(ctx context.Context, key string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	return rs.rdb.Del(ctx, failuresKey(key)).Err()
+}
+
+func (rs *RedisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	return rs.rdb.Set(ctx, lockKey(key), "1", d).Err()
+}
+
+func (rs *RedisStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	d, err := rs.rdb.PTTL(ctx, lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL reports missing keys with a negative duration
+	if d < 0 {
+		return 0, nil
+	}
+	return d, nil
+}
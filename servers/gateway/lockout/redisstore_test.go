@@ -0,0 +1,52 @@
+//go:build !no_db
+
+package lockout
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("REDISADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: addr}), time.Minute)
+	store.Reset(context.Background(), "test")
+	store.rdb.Del(context.Background(), lockKey("test"))
+
+	for i := 1; i <= 2; i++ {
+		failures, err := store.Fail(context.Background(), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failures != i {
+			t.Errorf("expected %d failures but got %d", i, failures)
+		}
+	}
+
+	wait, err := store.LockedFor(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != 0 {
+		t.Errorf("expected no lockout but got %s", wait)
+	}
+
+	err = store.Lock(context.Background(), "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait, err = store.LockedFor(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("expected a lockout of up to a minute but got %s", wait)
+	}
+}
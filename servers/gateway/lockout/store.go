@@ -0,0 +1,22 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout is how long a RedisStore waits for each call by default.
+const DefaultTimeout = 3 * time.Second
+
+// Store keeps failed sign-in counters and lockouts. Keys identify what is
+// being counted, such as an email address or a client IP.
+type Store interface {
+	// Fail records a failure for key and returns the number of failures
+	// since the last Reset. Counters expire on their own after a while.
+	Fail(ctx context.Context, key string) (int, error)
+	Reset(ctx context.Context, key string) error
+	// Lock locks key out for d.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// LockedFor returns how much longer key is locked out, or zero.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+}
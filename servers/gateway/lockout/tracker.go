@@ -0,0 +1,82 @@
+package lockout
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Tracker locks keys out after too many failed sign-ins. Once a key has
+// failed threshold times, every further failure locks it out for twice as
+// long as the previous one, starting at base and capped at max.
+type Tracker struct {
+	store     Store
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	audit     *log.Logger
+}
+
+func NewTracker(store Store, threshold int, base time.Duration, max time.Duration, audit *log.Logger) *Tracker {
+	return &Tracker{
+		store:     store,
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		audit:     audit,
+	}
+}
+
+// Check returns how long the longest lockout of any of keys has left.
+func (t *Tracker) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range keys {
+		d, err := t.store.LockedFor(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		wait = max(wait, d)
+	}
+	return wait, nil
+}
+
+func (t *Tracker) backoff(failures int) time.Duration {
+	d := t.base
+	for i := t.threshold; i < failures && d < t.max; i++ {
+		d *= 2
+	}
+	return min(d, t.max)
+}
+
+// Failure records a failed sign-in for each of keys and locks out any
+// that have reached the threshold.
+func (t *Tracker) Failure(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		failures, err := t.store.Fail(ctx, key)
+		if err != nil {
+			return err
+		}
+		if failures < t.threshold {
+			continue
+		}
+
+		d := t.backoff(failures)
+		err = t.store.Lock(ctx, key, d)
+		if err != nil {
+			return err
+		}
+		t.audit.Printf("lockout key=%s failures=%d duration=%s", key, failures, d)
+	}
+	return nil
+}
+
+// Success clears the failure counters of keys after a successful sign-in.
+func (t *Tracker) Success(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		err := t.store.Reset(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package lockout
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTracker() (*Tracker, *MemoryStore, *bytes.Buffer) {
+	audit := &bytes.Buffer{}
+	store := NewMemoryStore(time.Hour)
+	return NewTracker(store, 3, time.Minute, 5*time.Minute, log.New(audit, "", 0)), store, audit
+}
+
+func TestTrackerLockout(t *testing.T) {
+	tracker, _, audit := newTestTracker()
+
+	for i := 0; i < 2; i++ {
+		err := tracker.Failure(context.Background(), "email:a")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wait, err := tracker.Check(context.Background(), "email:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != 0 {
+		t.Errorf("expected no lockout before the threshold but got %s", wait)
+	}
+
+	err = tracker.Failure(context.Background(), "email:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait, _ = tracker.Check(context.Background(), "ip:b", "email:a")
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("expected a lockout of up to a minute but got %s", wait)
+	}
+	if !strings.Contains(audit.String(), "lockout key=email:a failures=3") {
+		t.Errorf("expected an audit entry for the lockout, got %q", audit.String())
+	}
+}
+
+func TestTrackerBackoff(t *testing.T) {
+	tracker, _, _ := newTestTracker()
+
+	cases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 5 * time.Minute},
+		{20, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if d := tracker.backoff(c.failures); d != c.expected {
+			t.Errorf("expected backoff of %s after %d failures but got %s", c.expected, c.failures, d)
+		}
+	}
+}
+
+func TestTrackerSuccess(t *testing.T) {
+	tracker, store, _ := newTestTracker()
+
+	for i := 0; i < 2; i++ {
+		tracker.Failure(context.Background(), "email:a")
+	}
+	err := tracker.Success(context.Background(), "email:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failures, _ := store.Fail(context.Background(), "email:a")
+	if failures != 1 {
+		t.Errorf("expected failures to restart after a success, got %d", failures)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	store.Fail(context.Background(), "a")
+	store.Lock(context.Background(), "a", time.Minute)
+
+	now = now.Add(time.Hour)
+	failures, _ := store.Fail(context.Background(), "a")
+	if failures != 1 {
+		t.Errorf("expected failures to expire after the window, got %d", failures)
+	}
+	wait, _ := store.LockedFor(context.Background(), "a")
+	if wait != 0 {
+		t.Errorf("expected the lockout to have expired, got %s", wait)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	store.Fail(context.Background(), "ip:192.0.2.1")
+	store.Lock(context.Background(), "ip:192.0.2.1", time.Minute)
+	store.Fail(context.Background(), "ip:192.0.2.2")
+
+	now = now.Add(30 * time.Minute)
+	store.Fail(context.Background(), "ip:192.0.2.2")
+	if swept := store.Sweep(); swept != 1 {
+		t.Errorf("expected only the expired lockout to be swept, got %d", swept)
+	}
+
+	now = now.Add(time.Hour)
+	if swept := store.Sweep(); swept != 2 {
+		t.Errorf("expected both expired counters to be swept, got %d", swept)
+	}
+	if len(store.counters) != 0 || len(store.locks) != 0 {
+		t.Errorf("expected nothing left after sweeping, got %v and %v", store.counters, store.locks)
+	}
+}
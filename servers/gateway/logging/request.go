@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// Request holds what is known about the request being served. Handlers
+// fill in UserID once they have authenticated the request.
+type Request struct {
+	ID     string
+	UserID int
+}
+
+// NewContext returns a copy of ctx that carries req.
+func NewContext(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, contextKey{}, req)
+}
+
+// FromContext returns the request carried by ctx, or nil.
+func FromContext(ctx context.Context) *Request {
+	req, _ := ctx.Value(contextKey{}).(*Request)
+	return req
+}
+
+// RequestID returns the ID of the request carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	if req := FromContext(ctx); req != nil {
+		return req.ID
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request carried by ctx.
+func SetUserID(ctx context.Context, userID int) {
+	if req := FromContext(ctx); req != nil {
+		req.UserID = userID
+	}
+}
+
+// Logger returns the default logger annotated with the request ID
+// carried by ctx, if any.
+func Logger(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With(slog.String("requestId", id))
+	}
+	return slog.Default()
+}
+
+// NewRequestID returns a random 128-bit request ID.
+func NewRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestContext(t *testing.T) {
+	ctx := context.Background()
+	if RequestID(ctx) != "" {
+		t.Error("expected no request ID without a request")
+	}
+	SetUserID(ctx, 3) // must not panic without a request
+
+	req := &Request{ID: "abc"}
+	ctx = NewContext(ctx, req)
+	if RequestID(ctx) != "abc" {
+		t.Errorf("expected request ID abc but got %q", RequestID(ctx))
+	}
+
+	SetUserID(ctx, 3)
+	if req.UserID != 3 {
+		t.Errorf("expected user ID 3 but got %d", req.UserID)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if len(a) != 32 {
+		t.Errorf("expected a 32 character ID but got %q", a)
+	}
+	if a == b {
+		t.Error("expected request IDs to differ")
+	}
+}
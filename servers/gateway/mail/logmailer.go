@@ -0,0 +1,25 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// LogMailer writes each email to a writer instead of delivering it, for
+// local development and tests.
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func (m *LogMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\n\n%s\n\n", to, subject, body)
+	return err
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mailer := NewLogMailer(buf)
+
+	err := mailer.Send("jon@example.com", "Hello", "Body text")
+	if err != nil {
+		t.Fatalf("error sending mail: %s", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"To: jon@example.com", "Subject: Hello", "Body text"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := NewSMTPMailer("localhost:25", "noreply@example.com", "", "")
+
+	err := mailer.Send("jon@example.com\r\nBcc: eve@example.com", "Hello", "Body")
+	if err == nil {
+		t.Error("expected an error for a recipient containing a newline")
+	}
+}
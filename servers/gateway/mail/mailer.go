@@ -0,0 +1,6 @@
+package mail
+
+// Mailer delivers plain-text email.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
@@ -1,19 +1,42 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"messaging-application/servers/gateway/handlers"
+	"messaging-application/servers/gateway/lockout"
+	"messaging-application/servers/gateway/mail"
+	"messaging-application/servers/gateway/metrics"
+	"messaging-application/servers/gateway/migrations"
+	"messaging-application/servers/gateway/models/signins"
 	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/notify"
+	"messaging-application/servers/gateway/ratelimit"
+	"messaging-application/servers/gateway/retry"
 	"messaging-application/servers/gateway/sessions"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
-
-	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	logHandler := slog.NewJSONHandler(os.Stdout, nil)
+	slog.SetDefault(slog.New(logHandler))
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrateCommand(os.Args[2:])
+		return
+	}
+	migrate := flag.Bool("migrate", false, "apply pending migrations before serving")
+	flag.Parse()
+
 	ADDR := os.Getenv("ADDR")
 	if len(ADDR) == 0 {
 		ADDR = ":443"
@@ -29,47 +52,324 @@ func main() {
 		log.Fatal("No TLSKEY environment variable found")
 	}
 
+	// SESSIONKEY is the key new session tokens, reset codes and email
+	// change links are signed with, written as ID:SECRET or as SECRET alone
+	// for key 0. To rotate it, give the new key a new ID and move the old
+	// key to SESSIONPREVIOUSKEYS, written as ID:SECRET@RFC3339 with the
+	// time it stops verifying existing tokens.
 	SESSIONKEY := os.Getenv("SESSIONKEY")
 	if len(SESSIONKEY) == 0 {
 		log.Fatal("No SESSIONKEY environment variable found")
 	}
+	sessionKey, err := sessions.ParseKey(SESSIONKEY)
+	if err != nil {
+		log.Fatalf("error parsing SESSIONKEY: %v", err)
+	}
 
-	REDISADDR := os.Getenv("REDISADDR")
-	if len(REDISADDR) == 0 {
-		log.Fatal("No REDISADDR environment variable found")
+	previousKeys := []sessions.Key{}
+	if v := os.Getenv("SESSIONPREVIOUSKEYS"); len(v) != 0 {
+		for _, text := range strings.Split(v, ",") {
+			key, err := sessions.ParseKey(text)
+			if err != nil {
+				log.Fatalf("error parsing SESSIONPREVIOUSKEYS: %v", err)
+			}
+			previousKeys = append(previousKeys, key)
+		}
 	}
 
+	sessionKeys, err := sessions.NewKeyring(sessionKey, previousKeys)
+	if err != nil {
+		log.Fatalf("error loading session keys: %v", err)
+	}
+
+	// SESSIONLEGACYUNTIL is the RFC 3339 time until which session tokens
+	// from before key IDs were added are still accepted. They are
+	// rejected if it is left out.
+	if v := os.Getenv("SESSIONLEGACYUNTIL"); len(v) != 0 {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Fatalf("error parsing SESSIONLEGACYUNTIL: %v", err)
+		}
+		sessionKeys.LegacyUntil = t
+	}
+
+	// SESSIONLIFETIME is how long a session lasts after signing in, however
+	// recently it was used
+	if v := os.Getenv("SESSIONLIFETIME"); len(v) != 0 {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("error parsing SESSIONLIFETIME: %v", err)
+		}
+		sessionKeys.TokenLifetime = d
+	}
+
+	// REDISADDR may be left out for a single gateway with a SQLite or
+	// memory: DSN, which then keeps its sessions in SQLite or in memory and
+	// its other state in memory
+	REDISADDR := os.Getenv("REDISADDR")
+
 	DSN := os.Getenv("DSN")
 	if len(DSN) == 0 {
 		log.Fatal("No DSN environment variable found")
 	}
 
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: REDISADDR,
-	})
-	redisStore := sessions.NewRedisStore(redisClient, "1h")
+	// MESSAGESADDR may be left out to serve only the gateway's own routes
+	MESSAGESADDR := os.Getenv("MESSAGESADDR")
+
+	ADMINADDR := os.Getenv("ADMINADDR")
+	if len(ADMINADDR) == 0 {
+		ADMINADDR = ":9090"
+	}
+
+	// DRAINTIMEOUT is how long in-flight requests have to finish on shutdown
+	DRAINTIMEOUT := 30 * time.Second
+	if v := os.Getenv("DRAINTIMEOUT"); len(v) != 0 {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("error parsing DRAINTIMEOUT: %v", err)
+		}
+		DRAINTIMEOUT = d
+	}
+
+	// STORETIMEOUT overrides the stores' default bound on each call to
+	// Redis or the database
+	var STORETIMEOUT time.Duration
+	if v := os.Getenv("STORETIMEOUT"); len(v) != 0 {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("error parsing STORETIMEOUT: %v", err)
+		}
+		STORETIMEOUT = d
+	}
 
-	time.Sleep(10 * time.Second) // wait for database to start up
-	db, err := sql.Open("mysql", DSN)
+	// TRUSTEDPROXIES lists the proxies in front of the gateway, whose
+	// X-Forwarded-For headers are believed, for example 10.0.0.0/8
+	trustedProxies, err := handlers.ParseTrustedProxies(os.Getenv("TRUSTEDPROXIES"))
 	if err != nil {
-		log.Fatalf("error opening db: %v", err)
+		log.Fatalf("error parsing TRUSTEDPROXIES: %v", err)
 	}
-	mysqlStore, err := users.NewMySQLStore(db)
+
+	// RATELIMITS lists the rate limit of each route prefix, for example
+	// /v1/sessions=10/1m,/=300/1m
+	RATELIMITS := os.Getenv("RATELIMITS")
+	if len(RATELIMITS) == 0 {
+		RATELIMITS = "/v1/sessions=10/1m,/v1/users=30/1m,/=300/1m"
+	}
+	rates, err := ratelimit.ParseRates(RATELIMITS)
 	if err != nil {
-		log.Fatalf("error creating mysql store: %v", err)
+		log.Fatalf("error parsing RATELIMITS: %v", err)
+	}
+
+	var mailer mail.Mailer = mail.NewLogMailer(os.Stdout)
+	if SMTPADDR := os.Getenv("SMTPADDR"); len(SMTPADDR) != 0 {
+		MAILFROM := os.Getenv("MAILFROM")
+		if len(MAILFROM) == 0 {
+			log.Fatal("No MAILFROM environment variable found")
+		}
+		mailer = mail.NewSMTPMailer(SMTPADDR, MAILFROM, os.Getenv("SMTPUSER"), os.Getenv("SMTPPASS"))
+	} else if MAILFILE := os.Getenv("MAILFILE"); len(MAILFILE) != 0 {
+		f, err := os.OpenFile(MAILFILE, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Fatalf("error opening mail file: %v", err)
+		}
+		defer f.Close()
+		mailer = mail.NewLogMailer(f)
 	}
 
-	hctx := handlers.NewHandlerContext(SESSIONKEY, &redisStore, &mysqlStore)
+	// A memory: DSN keeps users in memory, so no database is opened
+	snapshotPath, inMemory := strings.CutPrefix(DSN, memoryPrefix)
+	var db *sql.DB
+	var dialect migrations.Dialect
+	if !inMemory {
+		db, dialect, err = openDB(DSN)
+		if err != nil {
+			log.Fatalf("error opening db: %v", err)
+		}
+	}
+	local := len(REDISADDR) == 0
+	if local && !inMemory && dialect.Name != migrations.SQLite.Name {
+		log.Fatal("No REDISADDR environment variable found")
+	}
+
+	var backend backends
+	if !local {
+		backend = newRedisBackends(REDISADDR, STORETIMEOUT, sessionKeys.TokenLifetime)
+	}
+
+	// Wait for Redis and the database to start up
+	startup := retry.Backoff{Attempts: 8, Base: time.Second, Max: 16 * time.Second}
+	pings := map[string]func(context.Context) error{}
+	if db != nil {
+		pings[dialect.Name] = db.PingContext
+	}
+	if backend.ping != nil {
+		pings["redis"] = backend.ping
+	}
+	for name, ping := range pings {
+		err := startup.Do(context.Background(), func() error {
+			return ping(context.Background())
+		}, func(attempt int, err error, wait time.Duration) {
+			log.Printf("error pinging %s (attempt %d), retrying in %s: %v", name, attempt, wait, err)
+		})
+		if err != nil {
+			log.Fatalf("error pinging %s: %v", name, err)
+		}
+	}
+
+	// Nothing else can migrate a SQLite database, so the gateway always does
+	if db != nil && (*migrate || dialect.Name == migrations.SQLite.Name) {
+		migrator, err := newMigrator(db, dialect)
+		if err != nil {
+			log.Fatalf("error loading migrations: %v", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		for _, migration := range applied {
+			log.Printf("applied migration %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("error applying migrations: %v", err)
+		}
+	}
+
+	var baseUserStore users.Store
+	var signInStore signins.Store
+	var memoryUsers *users.MemoryStore
+	if inMemory {
+		memoryUsers, signInStore, err = newMemoryStores(snapshotPath)
+		if err != nil {
+			log.Fatalf("error creating memory stores: %v", err)
+		}
+		baseUserStore = memoryUsers
+	} else {
+		baseUserStore, signInStore, err = newSQLStores(db, dialect, STORETIMEOUT)
+		if err != nil {
+			log.Fatalf("error creating %s stores: %v", dialect.Name, err)
+		}
+	}
+
+	if local {
+		backend, err = newLocalBackends(db, STORETIMEOUT)
+		if err != nil {
+			log.Fatalf("error creating local backends: %v", err)
+		}
+	}
+
+	subscription, err := backend.events.Subscribe(context.Background())
+	if err != nil {
+		log.Fatalf("error subscribing to events: %v", err)
+	}
+	hub := notify.NewHub()
+	go hub.Run(subscription)
+
+	gatewayMetrics := metrics.NewMetrics()
+	gatewayMetrics.RegisterGauge("gateway_websocket_connections", "Open WebSocket connections.", func() float64 {
+		return float64(hub.TotalConnections())
+	})
+	handlers.SummaryClient.Transport = gatewayMetrics.InstrumentFetches(http.DefaultTransport)
+	sessionStore := gatewayMetrics.InstrumentSessionStore(backend.sessions)
+	userStore := gatewayMetrics.InstrumentUserStore(baseUserStore)
+
+	hctx := handlers.NewHandlerContext(sessionKeys, sessionStore, userStore, hub, mailer)
+	hctx.ResetCodes = backend.resetCodes
+	hctx.ResetLimiter = backend.newLimiter("ratelimit:reset", ratelimit.Rate{Limit: 5, Period: 15 * time.Minute})
+	auditLog := slog.NewLogLogger(logHandler.WithAttrs([]slog.Attr{slog.String("log", "audit")}), slog.LevelWarn)
+	hctx.SignInStore = signInStore
+	hctx.SignInTracker = lockout.NewTracker(backend.signInCounters, 5, time.Minute, 24*time.Hour, auditLog)
+	hctx.TrustedProxies = trustedProxies
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/summary", handlers.SummaryHandler)
 	mux.HandleFunc("/v1/users", hctx.UsersHandler)
 	mux.HandleFunc("/v1/users/{UserID}", hctx.SpecificUserHandler)
+	mux.HandleFunc("/v1/users/me/email/verify", hctx.EmailVerificationHandler)
+	mux.HandleFunc("/v1/users/me/signins", hctx.SignInsHandler)
 	mux.HandleFunc("/v1/sessions", hctx.SessionsHandler)
 	mux.HandleFunc("/v1/sessions/{SessionID}", hctx.SpecificSessionHandler)
+	mux.HandleFunc("/v1/resetcodes", hctx.ResetCodesHandler)
+	mux.HandleFunc("/v1/passwords/{Email}", hctx.PasswordsHandler)
+	mux.HandleFunc("/v1/ws", hctx.WebSocketHandler)
+
+	if len(MESSAGESADDR) != 0 {
+		messagesProxy, err := hctx.ServiceProxy(MESSAGESADDR)
+		if err != nil {
+			log.Fatalf("error creating messages proxy: %v", err)
+		}
+		mux.Handle("/v1/channels", messagesProxy)
+		mux.Handle("/v1/channels/", messagesProxy)
+		mux.Handle("/v1/messages/", messagesProxy)
+	} else {
+		log.Printf("no MESSAGESADDR set, not serving channels and messages")
+	}
+
+	routeLimits := []handlers.RouteLimit{}
+	for prefix, rate := range rates {
+		routeLimits = append(routeLimits, handlers.RouteLimit{
+			Prefix:  prefix,
+			Limiter: backend.newLimiter("ratelimit:route:"+prefix, rate),
+		})
+	}
+
+	handler := handlers.NewLoggingHandler(
+		handlers.NewMetricsHandler(handlers.NewCORSHandler(hctx.NewRateLimitHandler(mux, routeLimits)), gatewayMetrics),
+		slog.Default(),
+	)
+
+	// Health checks bypass logging, metrics and rate limiting so that
+	// frequent probes neither flood the logs nor get throttled
+	rootMux := http.NewServeMux()
+	rootMux.Handle("/", handler)
+	rootMux.HandleFunc("/healthz", handlers.HealthzHandler)
+	// Why a check failed is only shown on the admin address, since errors
+	// can give away addresses and versions of the backends
+	publicReadyz := handlers.NewReadyzHandler(pings)
+	publicReadyz.Public = true
+	rootMux.Handle("/readyz", publicReadyz)
+
+	// Metrics are served on a separate address that is not exposed publicly
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", gatewayMetrics.Handler())
+	adminMux.Handle("/readyz", handlers.NewReadyzHandler(pings))
 
-	handler := handlers.NewCORSHandler(mux)
+	server := &http.Server{Addr: ADDR, Handler: rootMux}
+	adminServer := &http.Server{Addr: ADMINADDR, Handler: adminMux}
 
-	log.Printf("server is listening at %s...", ADDR)
-	log.Fatal(http.ListenAndServeTLS(ADDR, TLSCERT, TLSKEY, handler))
+	go func() {
+		log.Printf("admin server is listening at %s...", ADMINADDR)
+		if err := adminServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	go func() {
+		log.Printf("server is listening at %s...", ADDR)
+		if err := server.ListenAndServeTLS(TLSCERT, TLSKEY); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+
+	log.Printf("shutting down, draining requests for up to %s...", DRAINTIMEOUT)
+	drainCtx, cancel := context.WithTimeout(context.Background(), DRAINTIMEOUT)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		log.Printf("error shutting down server: %v", err)
+	}
+	if err := adminServer.Shutdown(drainCtx); err != nil {
+		log.Printf("error shutting down admin server: %v", err)
+	}
+	if err := backend.close(); err != nil {
+		log.Printf("error closing backends: %v", err)
+	}
+	if db != nil {
+		if err := db.Close(); err != nil {
+			log.Printf("error closing db: %v", err)
+		}
+	}
+	if memoryUsers != nil && len(snapshotPath) != 0 {
+		if err := memoryUsers.SaveFile(snapshotPath); err != nil {
+			log.Printf("error saving users snapshot: %v", err)
+		}
+	}
 }
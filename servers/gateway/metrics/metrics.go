@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the gateway's Prometheus collectors in their own registry.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storeDuration   *prometheus.HistogramVec
+	storeErrors     *prometheus.CounterVec
+	fetches         *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_http_requests_total",
+			Help: "HTTP requests served, by route pattern and status.",
+		}, []string{"pattern", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gateway_http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by route pattern and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"pattern", "status"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gateway_store_call_duration_seconds",
+			Help:    "Time taken by session and user store calls, by store and operation.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"store", "operation"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_store_call_errors_total",
+			Help: "Session and user store calls that failed, by store and operation.",
+		}, []string{"store", "operation"}),
+		fetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_summary_fetches_total",
+			Help: "Pages fetched for link summaries, by outcome.",
+		}, []string{"outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storeDuration,
+		m.storeErrors,
+		m.fetches,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a served request. Requests that matched no
+// route are grouped under the pattern "none" to bound the label values.
+func (m *Metrics) ObserveRequest(pattern string, status int, d time.Duration) {
+	if pattern == "" {
+		pattern = "none"
+	}
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(pattern, code).Inc()
+	m.requestDuration.WithLabelValues(pattern, code).Observe(d.Seconds())
+}
+
+// ObserveStoreCall records a store call that took d and failed if failed
+// is true.
+func (m *Metrics) ObserveStoreCall(store string, operation string, d time.Duration, failed bool) {
+	m.storeDuration.WithLabelValues(store, operation).Observe(d.Seconds())
+	if failed {
+		m.storeErrors.WithLabelValues(store, operation).Inc()
+	}
+}
+
+// RegisterGauge adds a gauge whose value is read from value at scrape time.
+func (m *Metrics) RegisterGauge(name string, help string, value func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: name,
+		Help: help,
+	}, value))
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/sessions"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the metrics as served to Prometheus.
+func scrape(t *testing.T, m *Metrics) string {
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func expectSeries(t *testing.T, body string, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(body, s) {
+			t.Errorf("expected metrics to contain %s", s)
+		}
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := NewMetrics()
+	m.ObserveRequest("/v1/users/{UserID}", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest("", http.StatusNotFound, time.Millisecond)
+	m.RegisterGauge("gateway_websocket_connections", "Open WebSocket connections.", func() float64 { return 3 })
+
+	expectSeries(t, scrape(t, m),
+		`gateway_http_requests_total{pattern="/v1/users/{UserID}",status="200"} 1`,
+		`gateway_http_requests_total{pattern="none",status="404"} 1`,
+		`gateway_http_request_duration_seconds_count{pattern="/v1/users/{UserID}",status="200"} 1`,
+		`gateway_websocket_connections 3`,
+	)
+}
+
+func TestInstrumentedStores(t *testing.T) {
+	m := NewMetrics()
+	sessionStore := m.InstrumentSessionStore(sessions.NewMemoryStore("1h"))
+	userStore := m.InstrumentUserStore(users.NewMemoryStore())
+
+	sessionStore.Set(context.Background(), "a", "state")
+	sessionStore.Get(context.Background(), "a")
+	sessionStore.Get(context.Background(), "missing")
+	userStore.GetByID(context.Background(), 1)
+
+	body := scrape(t, m)
+	expectSeries(t, body,
+		`gateway_store_call_duration_seconds_count{operation="Get",store="sessions"} 2`,
+		`gateway_store_call_duration_seconds_count{operation="Set",store="sessions"} 1`,
+		`gateway_store_call_duration_seconds_count{operation="GetByID",store="users"} 1`,
+	)
+	if strings.Contains(body, "gateway_store_call_errors_total{") {
+		t.Error("expected missing sessions and users not to count as errors")
+	}
+}
+
+func TestInstrumentFetches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	m := NewMetrics()
+	client := &http.Client{Transport: m.InstrumentFetches(http.DefaultTransport)}
+	for _, url := range []string{server.URL, server.URL + "/missing", "http://localhost:99999"} {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	expectSeries(t, scrape(t, m),
+		`gateway_summary_fetches_total{outcome="success"} 1`,
+		`gateway_summary_fetches_total{outcome="http_error"} 1`,
+		`gateway_summary_fetches_total{outcome="error"} 1`,
+	)
+}
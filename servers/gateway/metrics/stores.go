@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/sessions"
+	"time"
+)
+
+// SessionStore measures the calls made to a sessions.Store.
+type SessionStore struct {
+	store   sessions.Store
+	metrics *Metrics
+}
+
+func (m *Metrics) InstrumentSessionStore(store sessions.Store) *SessionStore {
+	return &SessionStore{store: store, metrics: m}
+}
+
+// observe records a call that started at start. Missing sessions are an
+// expected outcome rather than a failure.
+func (s *SessionStore) observe(operation string, start time.Time, err error) {
+	failed := err != nil && !errors.Is(err, sessions.ErrStateNotFound)
+	s.metrics.ObserveStoreCall("sessions", operation, time.Since(start), failed)
+}
+
+func (s *SessionStore) Get(ctx context.Context, key string) (string, error) {
+	start := time.Now()
+	val, err := s.store.Get(ctx, key)
+	s.observe("Get", start, err)
+	return val, err
+}
+
+func (s *SessionStore) Set(ctx context.Context, key string, value string) error {
+	start := time.Now()
+	err := s.store.Set(ctx, key, value)
+	s.observe("Set", start, err)
+	return err
+}
+
+func (s *SessionStore) Replace(ctx context.Context, key string, value string) error {
+	start := time.Now()
+	err := s.store.Replace(ctx, key, value)
+	s.observe("Replace", start, err)
+	return err
+}
+
+func (s *SessionStore) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, key)
+	s.observe("Delete", start, err)
+	return err
+}
+
+func (s *SessionStore) AddSessionID(ctx context.Context, userID int, sessionID string) error {
+	start := time.Now()
+	err := s.store.AddSessionID(ctx, userID, sessionID)
+	s.observe("AddSessionID", start, err)
+	return err
+}
+
+func (s *SessionStore) RemoveSessionID(ctx context.Context, userID int, sessionID string) error {
+	start := time.Now()
+	err := s.store.RemoveSessionID(ctx, userID, sessionID)
+	s.observe("RemoveSessionID", start, err)
+	return err
+}
+
+func (s *SessionStore) GetSessionIDs(ctx context.Context, userID int) ([]string, error) {
+	start := time.Now()
+	ids, err := s.store.GetSessionIDs(ctx, userID)
+	s.observe("GetSessionIDs", start, err)
+	return ids, err
+}
+
+// UserStore measures the calls made to a users.Store.
+type UserStore struct {
+	store   users.Store
+	metrics *Metrics
+}
+
+func (m *Metrics) InstrumentUserStore(store users.Store) *UserStore {
+	return &UserStore{store: store, metrics: m}
+}
+
+// observe records a call that started at start. Lookups that find no user
+// and rejected duplicates are expected outcomes rather than failures.
+func (s *UserStore) observe(operation string, start time.Time, err error) {
+	failed := err != nil && !errors.Is(err, users.ErrUserNotFound) && !errors.Is(err, users.ErrDuplicate)
+	s.metrics.ObserveStoreCall("users", operation, time.Since(start), failed)
+}
+
+func (s *UserStore) Insert(ctx context.Context, user *users.User) (*users.User, error) {
+	start := time.Now()
+	inserted, err := s.store.Insert(ctx, user)
+	s.observe("Insert", start, err)
+	return inserted, err
+}
+
+func (s *UserStore) GetByID(ctx context.Context, id int) (*users.User, error) {
+	start := time.Now()
+	user, err := s.store.GetByID(ctx, id)
+	s.observe("GetByID", start, err)
+	return user, err
+}
+
+func (s *UserStore) GetByEmail(ctx context.Context, email string) (*users.User, error) {
+	start := time.Now()
+	user, err := s.store.GetByEmail(ctx, email)
+	s.observe("GetByEmail", start, err)
+	return user, err
+}
+
+func (s *UserStore) Update(ctx context.Context, id int, user *users.User) (*users.User, error) {
+	start := time.Now()
+	updated, err := s.store.Update(ctx, id, user)
+	s.observe("Update", start, err)
+	return updated, err
+}
+
+func (s *UserStore) GetByPrefix(ctx context.Context, prefix string, max int) ([]*users.User, error) {
+	start := time.Now()
+	found, err := s.store.GetByPrefix(ctx, prefix, max)
+	s.observe("GetByPrefix", start, err)
+	return found, err
+}
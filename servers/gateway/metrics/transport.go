@@ -0,0 +1,28 @@
+package metrics
+
+import "net/http"
+
+type fetchTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+// InstrumentFetches counts the outcomes of requests sent through next:
+// success for 2xx and 3xx responses, http_error for other responses and
+// error when no response was received.
+func (m *Metrics) InstrumentFetches(next http.RoundTripper) http.RoundTripper {
+	return &fetchTransport{next: next, metrics: m}
+}
+
+func (t *fetchTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(r)
+	switch {
+	case err != nil:
+		t.metrics.fetches.WithLabelValues("error").Inc()
+	case resp.StatusCode >= 400:
+		t.metrics.fetches.WithLabelValues("http_error").Inc()
+	default:
+		t.metrics.fetches.WithLabelValues("success").Inc()
+	}
+	return resp, err
+}
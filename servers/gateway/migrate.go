@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"messaging-application/servers/gateway/migrations"
+	"os"
+	"strings"
+)
+
+const migrateUsage = "usage: gateway migrate up|down|status"
+
+// newMigrator returns a migrator for the embedded migrations of dialect.
+func newMigrator(db *sql.DB, dialect migrations.Dialect) (*migrations.Migrator, error) {
+	all, err := migrations.Embedded(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.NewMigrator(db, dialect, all)
+}
+
+// migrateCommand runs the migrate subcommand against the database in the
+// DSN environment variable. A MySQL DSN does not need parseTime=true,
+// since openDB adds it for reading when migrations were applied.
+func migrateCommand(args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	DSN := os.Getenv("DSN")
+	if len(DSN) == 0 {
+		log.Fatal("No DSN environment variable found")
+	}
+	if strings.HasPrefix(DSN, memoryPrefix) {
+		log.Fatal("A memory: DSN has no database to migrate")
+	}
+	db, dialect, err := openDB(DSN)
+	if err != nil {
+		log.Fatalf("error opening db: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := newMigrator(db, dialect)
+	if err != nil {
+		log.Fatalf("error loading migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("error migrating up: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		undone, err := migrator.Down(ctx)
+		if errors.Is(err, migrations.ErrNoneApplied) {
+			fmt.Println("no migrations to roll back")
+			return
+		}
+		if err != nil {
+			log.Fatalf("error migrating down: %v", err)
+		}
+		fmt.Printf("rolled back %04d_%s\n", undone.Version, undone.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("error getting migration status: %v", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// pgLockKey identifies the Postgres advisory lock held while migrating.
+const pgLockKey = 7263498101
+
+// lockPollInterval is how often Postgres is asked for the lock again while
+// another replica holds it.
+const lockPollInterval = 500 * time.Millisecond
+
+// Dialect holds what differs between the databases migrations run on.
+// Each dialect has its own directory of migrations.
+type Dialect struct {
+	Name string
+
+	createTable string
+	lock        func(ctx context.Context, conn *sql.Conn, timeout time.Duration) (bool, error)
+	unlock      func(ctx context.Context, conn *sql.Conn) error
+	placeholder func(n int) string
+}
+
+var MySQL = Dialect{
+	Name:        "mysql",
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL)",
+	lock: func(ctx context.Context, conn *sql.Conn, timeout time.Duration) (bool, error) {
+		var locked sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&locked)
+		return locked.Int64 == 1, err
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+		return err
+	},
+	placeholder: func(n int) string {
+		return "?"
+	},
+}
+
+var Postgres = Dialect{
+	Name:        "postgres",
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+	lock: func(ctx context.Context, conn *sql.Conn, timeout time.Duration) (bool, error) {
+		// pg_advisory_lock cannot time out, so poll pg_try_advisory_lock
+		deadline := time.Now().Add(timeout)
+		for {
+			var locked bool
+			err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", pgLockKey).Scan(&locked)
+			if err != nil || locked || time.Now().After(deadline) {
+				return locked, err
+			}
+
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(lockPollInterval):
+			}
+		}
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", pgLockKey)
+		return err
+	},
+	placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+}
+
+// SQLite needs no lock, since a SQLite database belongs to a single
+// gateway process.
+var SQLite = Dialect{
+	Name:        "sqlite",
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL)",
+	lock: func(ctx context.Context, conn *sql.Conn, timeout time.Duration) (bool, error) {
+		return true, nil
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		return nil
+	},
+	placeholder: MySQL.placeholder,
+}
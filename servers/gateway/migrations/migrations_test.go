@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t (a);")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX idx ON t;")},
+		"0001_create.up.sql":      {Data: []byte("CREATE TABLE t (a INT);")},
+		"0001_create.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"README.md":               {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %s", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations but got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create" || migrations[0].Down != "DROP TABLE t;" {
+		t.Errorf("Unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[1].Version != 2 || migrations[1].Up != "CREATE INDEX idx ON t (a);" {
+		t.Errorf("Unexpected second migration: %+v", migrations[1])
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create.up.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+		},
+		"mismatched names": {
+			"0001_create.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+			"0001_other.down.sql":  {Data: []byte("DROP TABLE t;")},
+			"0001_create.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+	}
+
+	for name, fsys := range cases {
+		_, err := Load(fsys)
+		if err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+// sqliteOnly names the migrations that only SQLite has, for the state a
+// gateway without Redis keeps in its database instead.
+var sqliteOnly = []string{"sessions"}
+
+func TestEmbedded(t *testing.T) {
+	mysql, err := Embedded(MySQL)
+	if err != nil {
+		t.Fatalf("Error loading embedded MySQL migrations: %s", err)
+	}
+	postgres, err := Embedded(Postgres)
+	if err != nil {
+		t.Fatalf("Error loading embedded Postgres migrations: %s", err)
+	}
+	sqlite, err := Embedded(SQLite)
+	if err != nil {
+		t.Fatalf("Error loading embedded SQLite migrations: %s", err)
+	}
+	if len(mysql) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, migration := range sqlite {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d but got %d", i, i+1, migration.Version)
+		}
+	}
+
+	// Every other migration is the same for each dialect
+	shared := slices.DeleteFunc(slices.Clone(sqlite), func(migration Migration) bool {
+		return slices.Contains(sqliteOnly, migration.Name)
+	})
+	dialects := map[string][]Migration{"MySQL": mysql, "Postgres": postgres, "SQLite": sqlite}
+	for name, migrations := range dialects {
+		if name != "SQLite" && len(migrations) != len(shared) {
+			t.Errorf("Expected %d %s migrations but got %d", len(shared), name, len(migrations))
+			continue
+		}
+		for i, migration := range migrations {
+			if name != "SQLite" && (migration.Version != shared[i].Version || migration.Name != shared[i].Name) {
+				t.Errorf("Expected %s migration %d to be %s but got %s", name, migration.Version, shared[i].Name, migration.Name)
+			}
+			if len(statements(migration.Up)) == 0 || len(statements(migration.Down)) == 0 {
+				t.Errorf("%s migration %d has no statements", name, migration.Version)
+			}
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := "-- the first table\nCREATE TABLE a (\n  id INT\n);\n\nCREATE TABLE b (id INT);\nDROP TABLE c;"
+	expected := []string{"CREATE TABLE a (\n  id INT\n)", "CREATE TABLE b (id INT)", "DROP TABLE c"}
+
+	stmts := statements(script)
+	if !slices.Equal(stmts, expected) {
+		t.Errorf("Expected %q but got %q", expected, stmts)
+	}
+}
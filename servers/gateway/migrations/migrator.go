@@ -0,0 +1,193 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// lockName is the MySQL named lock held while migrating, so that replicas
+// starting at the same time take turns instead of racing. Postgres uses an
+// advisory lock instead.
+const lockName = "gateway.schema_migrations"
+
+// DefaultLockTimeout is how long a Migrator waits for another replica to
+// finish migrating by default.
+const DefaultLockTimeout = time.Minute
+
+// ErrLocked is returned when another process holds the migration lock for
+// longer than the lock timeout.
+var ErrLocked = errors.New("timed out waiting for the migration lock")
+
+// ErrNoneApplied is returned when rolling back a database that has no
+// migrations applied.
+var ErrNoneApplied = errors.New("no migrations have been applied")
+
+// Status is a migration and whether it has been applied.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back migrations, recording the applied ones
+// in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+
+	LockTimeout time.Duration
+}
+
+// NewMigrator returns a migrator for db. A MySQL db must be opened with
+// parseTime=true, since applied_at is scanned into a time.Time.
+func NewMigrator(db *sql.DB, dialect Dialect, migrations []Migration) (*Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db must not be nil")
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, LockTimeout: DefaultLockTimeout}, nil
+}
+
+// withLock runs fn on a single connection while holding the migration
+// lock. Named and advisory locks belong to the connection that took them.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	locked, err := m.dialect.lock(ctx, conn, m.LockTimeout)
+	if err != nil {
+		return fmt.Errorf("error taking the migration lock: %w", err)
+	}
+	if !locked {
+		return ErrLocked
+	}
+	defer m.dialect.unlock(context.WithoutCancel(ctx), conn)
+
+	_, err = conn.ExecContext(ctx, m.dialect.createTable)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// applied returns when each applied migration was applied, by version.
+func applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// run executes each statement of a migration script.
+func run(ctx context.Context, conn *sql.Conn, migration Migration, script string) error {
+	for _, stmt := range statements(script) {
+		_, err := conn.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("error running migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns the ones it
+// applied. MySQL cannot roll back schema changes, so a migration that fails
+// part way has to be fixed by hand before it is retried.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := []Migration{}
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			err = run(ctx, conn, migration, migration.Up)
+			if err != nil {
+				return err
+			}
+			p := m.dialect.placeholder
+			iq := "INSERT INTO schema_migrations (version, name, applied_at) VALUES (" + p(1) + ", " + p(2) + ", " + p(3) + ")"
+			_, err = conn.ExecContext(ctx, iq, migration.Version, migration.Name, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the most recently applied migration and returns it.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var undone *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			err = run(ctx, conn, migration, migration.Down)
+			if err != nil {
+				return err
+			}
+			dq := "DELETE FROM schema_migrations WHERE version = " + m.dialect.placeholder(1)
+			_, err = conn.ExecContext(ctx, dq, migration.Version)
+			if err != nil {
+				return err
+			}
+			undone = &migration
+			return nil
+		}
+		return ErrNoneApplied
+	})
+	return undone, err
+}
+
+// Status lists every migration, oldest first, with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	statuses := []Status{}
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
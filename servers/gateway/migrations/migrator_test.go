@@ -0,0 +1,168 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create", Up: "CREATE TABLE t (a INT);", Down: "DROP TABLE t;"},
+	{Version: 2, Name: "add_index", Up: "CREATE INDEX idx ON t (a);", Down: "DROP INDEX idx ON t;"},
+}
+
+func expectLock(mock sqlmock.Sqlmock, result any) {
+	mock.ExpectQuery(`SELECT GET_LOCK`).WithArgs(lockName, 60).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(result))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Now())
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestUpAppliesPending(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 1)
+	expectApplied(mock, 1)
+	mock.ExpectExec("CREATE INDEX idx ON t").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "add_index", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	done, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Error migrating up: %s", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Errorf("Expected only migration 2 to be applied but got %+v", done)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestUpStopsOnFailure(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 1)
+	expectApplied(mock)
+	mock.ExpectExec("CREATE TABLE t").WillReturnError(errors.New("syntax error"))
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	done, err := migrator.Up(context.Background())
+	if err == nil {
+		t.Errorf("Expected the failed migration to return an error")
+	}
+	if len(done) != 0 {
+		t.Errorf("Expected no migrations to be applied but got %+v", done)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestUpLocked(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 0)
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	_, err := migrator.Up(context.Background())
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked but got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestDownRollsBackLatest(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 1)
+	expectApplied(mock, 1, 2)
+	mock.ExpectExec("DROP INDEX idx ON t").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	undone, err := migrator.Down(context.Background())
+	if err != nil {
+		t.Fatalf("Error migrating down: %s", err)
+	}
+	if undone.Version != 2 {
+		t.Errorf("Expected migration 2 to be rolled back but got %d", undone.Version)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestDownNoneApplied(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 1)
+	expectApplied(mock)
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	_, err := migrator.Down(context.Background())
+	if !errors.Is(err, ErrNoneApplied) {
+		t.Errorf("Expected ErrNoneApplied but got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectLock(mock, 1)
+	expectApplied(mock, 1)
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, MySQL, testMigrations)
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting status: %s", err)
+	}
+	if len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestPostgresPlaceholders(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WithArgs(pgLockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	expectApplied(mock)
+	mock.ExpectExec("CREATE TABLE t").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name, applied_at\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(1, "create", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(pgLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator, _ := NewMigrator(db, Postgres, testMigrations[:1])
+	_, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Error migrating up: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
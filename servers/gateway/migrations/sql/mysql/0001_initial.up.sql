@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS users (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  first_name VARCHAR(255),
+  last_name VARCHAR(255),
+  username VARCHAR(255) NOT NULL,
+  email VARCHAR(320) NOT NULL,
+  photo_url VARCHAR(348),
+  pass_hash VARCHAR(72) NOT NULL,
+  UNIQUE INDEX idx_username (username),
+  UNIQUE INDEX idx_email (email)
+);
+
+CREATE TABLE IF NOT EXISTS channels (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  private BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME NOT NULL,
+  creator_id INT NOT NULL,
+  edited_at DATETIME,
+  FOREIGN KEY (creator_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS channel_members (
+  channel_id INT NOT NULL,
+  user_id INT NOT NULL,
+  PRIMARY KEY (channel_id, user_id),
+  FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+  id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  channel_id INT NOT NULL,
+  body TEXT NOT NULL,
+  created_at DATETIME NOT NULL,
+  creator_id INT NOT NULL,
+  edited_at DATETIME,
+  INDEX idx_messages_channel (channel_id, id),
+  FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE,
+  FOREIGN KEY (creator_id) REFERENCES users (id)
+);
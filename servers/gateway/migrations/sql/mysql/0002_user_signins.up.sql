@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_signins (
+  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  user_id INT,
+  signed_in_at DATETIME NOT NULL,
+  ip VARCHAR(45) NOT NULL,
+  user_agent VARCHAR(512) NOT NULL,
+  success BOOLEAN NOT NULL,
+  INDEX idx_user_signins_user (user_id, id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
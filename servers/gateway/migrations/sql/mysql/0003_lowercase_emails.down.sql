@@ -0,0 +1,3 @@
+-- The original case of each email is not kept, so there is nothing to
+-- undo.
+SELECT 1;
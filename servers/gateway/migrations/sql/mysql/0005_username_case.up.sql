@@ -0,0 +1,3 @@
+-- Usernames are already unique whatever their case under MySQL's default
+-- collation, which the other dialects now match.
+SELECT 1;
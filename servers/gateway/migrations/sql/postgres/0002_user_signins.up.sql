@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_signins (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT REFERENCES users (id) ON DELETE CASCADE,
+  signed_in_at TIMESTAMP NOT NULL,
+  ip VARCHAR(45) NOT NULL,
+  user_agent VARCHAR(512) NOT NULL,
+  success BOOLEAN NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_signins_user
+ON user_signins (user_id, id);
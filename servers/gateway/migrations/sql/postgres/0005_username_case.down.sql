@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_username;
+
+CREATE UNIQUE INDEX idx_username
+ON users (username);
@@ -0,0 +1,7 @@
+-- Usernames are unique whatever their case, as they are under MySQL's
+-- default collation. This fails if two accounts differ only by the case
+-- of their username, which have to be renamed by hand first.
+DROP INDEX IF EXISTS idx_username;
+
+CREATE UNIQUE INDEX idx_username
+ON users (LOWER(username));
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  first_name VARCHAR(255),
+  last_name VARCHAR(255),
+  username VARCHAR(255) NOT NULL,
+  email VARCHAR(320) NOT NULL,
+  photo_url VARCHAR(348),
+  pass_hash VARCHAR(72) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_username
+ON users (username);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email
+ON users (email);
+
+CREATE TABLE IF NOT EXISTS channels (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  private BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME NOT NULL,
+  creator_id INTEGER NOT NULL REFERENCES users (id),
+  edited_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS channel_members (
+  channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE,
+  body TEXT NOT NULL,
+  created_at DATETIME NOT NULL,
+  creator_id INTEGER NOT NULL REFERENCES users (id),
+  edited_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_channel
+ON messages (channel_id, id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_signins (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+  signed_in_at DATETIME NOT NULL,
+  ip VARCHAR(45) NOT NULL,
+  user_agent VARCHAR(512) NOT NULL,
+  success BOOLEAN NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_signins_user
+ON user_signins (user_id, id);
@@ -0,0 +1,4 @@
+-- Emails are stored lowercased so that they are found whatever case they
+-- are typed in. This fails if two accounts differ only by the case of
+-- their email, which have to be merged by hand first.
+UPDATE users SET email = LOWER(TRIM(email));
@@ -0,0 +1,16 @@
+-- Sessions are kept in Redis except by a gateway running on SQLite alone.
+-- expires_at is in Unix nanoseconds.
+CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT NOT NULL PRIMARY KEY,
+  state TEXT NOT NULL,
+  expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires
+ON sessions (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+  user_id INTEGER NOT NULL,
+  session_id TEXT NOT NULL,
+  PRIMARY KEY (user_id, session_id)
+);
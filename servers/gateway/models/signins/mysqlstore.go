@@ -0,0 +1,76 @@
+package signins
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"messaging-application/servers/gateway/timeout"
+	"time"
+)
+
+type MySQLStore struct {
+	db *sql.DB
+
+	// Timeout bounds each call to the database. Zero leaves calls bounded
+	// only by their context.
+	Timeout time.Duration
+}
+
+func NewMySQLStore(db *sql.DB) (MySQLStore, error) {
+	if db == nil {
+		return MySQLStore{}, fmt.Errorf("db must not be nil")
+	}
+	return MySQLStore{db: db, Timeout: DefaultTimeout}, nil
+}
+
+func (s *MySQLStore) Insert(ctx context.Context, signIn *SignIn) (*SignIn, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	userID := sql.NullInt64{Int64: int64(signIn.UserID), Valid: signIn.UserID != 0}
+	iq := "INSERT INTO user_signins (user_id, signed_in_at, ip, user_agent, success) VALUES (?, ?, ?, ?, ?)"
+	res, err := s.db.ExecContext(ctx, iq, userID, signIn.Time, signIn.IP, signIn.UserAgent, signIn.Success)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	inserted := *signIn
+	inserted.ID = id
+	return &inserted, nil
+}
+
+func (s *MySQLStore) GetByUserID(ctx context.Context, userID int, before int64, limit int) ([]*SignIn, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	q := "SELECT id, user_id, signed_in_at, ip, user_agent, success FROM user_signins WHERE user_id = ?"
+	args := []any{userID}
+	if before != 0 {
+		q += " AND id < ?"
+		args = append(args, before)
+	}
+	q += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := []*SignIn{}
+	for rows.Next() {
+		signIn := &SignIn{}
+		err = rows.Scan(&signIn.ID, &signIn.UserID, &signIn.Time, &signIn.IP, &signIn.UserAgent, &signIn.Success)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, signIn)
+	}
+	return found, rows.Err()
+}
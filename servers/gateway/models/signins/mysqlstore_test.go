@@ -0,0 +1,91 @@
+package signins
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var signInTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestShouldInsertSignIn(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO user_signins").
+		WithArgs(int64(3), signInTime, "203.0.113.7", "phone", true).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+
+	store := MySQLStore{db: db}
+	inserted, err := store.Insert(context.Background(), &SignIn{UserID: 3, Time: signInTime, IP: "203.0.113.7", UserAgent: "phone", Success: true})
+	if err != nil {
+		t.Errorf("Error inserting sign-in: %s", err)
+	}
+	if inserted.ID != 9 {
+		t.Errorf("Expected ID 9 but got %d", inserted.ID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShouldInsertUnknownUserSignIn(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO user_signins").
+		WithArgs(nil, signInTime, "203.0.113.7", "phone", false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	store := MySQLStore{db: db}
+	_, err := store.Insert(context.Background(), &SignIn{Time: signInTime, IP: "203.0.113.7", UserAgent: "phone"})
+	if err != nil {
+		t.Errorf("Error inserting sign-in: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShouldGetSignInsPage(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "signed_in_at", "ip", "user_agent", "success"})
+	rows.AddRow(8, 3, signInTime, "203.0.113.7", "phone", true)
+	rows.AddRow(5, 3, signInTime, "203.0.113.8", "tablet", false)
+	mock.ExpectQuery(`SELECT (.+) FROM user_signins WHERE user_id = \? AND id < \? ORDER BY id DESC LIMIT \?`).
+		WithArgs(3, int64(10), 2).
+		WillReturnRows(rows)
+
+	store := MySQLStore{db: db}
+	found, err := store.GetByUserID(context.Background(), 3, 10, 2)
+	if err != nil {
+		t.Errorf("Error getting sign-ins: %s", err)
+	}
+	if len(found) != 2 || found[0].ID != 8 || found[1].Success {
+		t.Errorf("Unexpected sign-ins: %+v", found)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetSignInsError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM user_signins").WillReturnError(errors.New("connection lost"))
+
+	store := MySQLStore{db: db}
+	_, err := store.GetByUserID(context.Background(), 3, 0, 20)
+	if err == nil {
+		t.Error("Expected an error getting sign-ins")
+	}
+}
@@ -0,0 +1,70 @@
+package signins
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"messaging-application/servers/gateway/timeout"
+	"strconv"
+	"time"
+)
+
+type PostgresStore struct {
+	db *sql.DB
+
+	// Timeout bounds each call to the database. Zero leaves calls bounded
+	// only by their context.
+	Timeout time.Duration
+}
+
+func NewPostgresStore(db *sql.DB) (PostgresStore, error) {
+	if db == nil {
+		return PostgresStore{}, fmt.Errorf("db must not be nil")
+	}
+	return PostgresStore{db: db, Timeout: DefaultTimeout}, nil
+}
+
+func (s *PostgresStore) Insert(ctx context.Context, signIn *SignIn) (*SignIn, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	userID := sql.NullInt64{Int64: int64(signIn.UserID), Valid: signIn.UserID != 0}
+	iq := "INSERT INTO user_signins (user_id, signed_in_at, ip, user_agent, success) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	inserted := *signIn
+	err := s.db.QueryRowContext(ctx, iq, userID, signIn.Time, signIn.IP, signIn.UserAgent, signIn.Success).Scan(&inserted.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &inserted, nil
+}
+
+func (s *PostgresStore) GetByUserID(ctx context.Context, userID int, before int64, limit int) ([]*SignIn, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	q := "SELECT id, user_id, signed_in_at, ip, user_agent, success FROM user_signins WHERE user_id = $1"
+	args := []any{userID}
+	if before != 0 {
+		args = append(args, before)
+		q += " AND id < $" + strconv.Itoa(len(args))
+	}
+	args = append(args, limit)
+	q += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := []*SignIn{}
+	for rows.Next() {
+		signIn := &SignIn{}
+		err = rows.Scan(&signIn.ID, &signIn.UserID, &signIn.Time, &signIn.IP, &signIn.UserAgent, &signIn.Success)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, signIn)
+	}
+	return found, rows.Err()
+}
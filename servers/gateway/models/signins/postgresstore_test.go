@@ -0,0 +1,60 @@
+package signins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresInsertSignIn(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO user_signins (.+) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`).
+		WithArgs(nil, signInTime, "203.0.113.7", "phone", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+	store := PostgresStore{db: db}
+	inserted, err := store.Insert(context.Background(), &SignIn{Time: signInTime, IP: "203.0.113.7", UserAgent: "phone"})
+	if err != nil {
+		t.Errorf("Error inserting sign-in: %s", err)
+	}
+	if inserted.ID != 4 {
+		t.Errorf("Expected ID 4 but got %d", inserted.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGetSignInsPage(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "signed_in_at", "ip", "user_agent", "success"})
+	rows.AddRow(8, 3, signInTime, "203.0.113.7", "phone", true)
+	mock.ExpectQuery(`SELECT (.+) FROM user_signins WHERE user_id = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+		WithArgs(3, int64(10), 2).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT (.+) FROM user_signins WHERE user_id = \$1 ORDER BY id DESC LIMIT \$2`).
+		WithArgs(3, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "signed_in_at", "ip", "user_agent", "success"}))
+
+	store := PostgresStore{db: db}
+	found, err := store.GetByUserID(context.Background(), 3, 10, 2)
+	if err != nil {
+		t.Errorf("Error getting sign-ins: %s", err)
+	}
+	if len(found) != 1 || found[0].ID != 8 {
+		t.Errorf("Unexpected sign-ins: %+v", found)
+	}
+
+	_, err = store.GetByUserID(context.Background(), 3, 0, 20)
+	if err != nil {
+		t.Errorf("Error getting first page of sign-ins: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
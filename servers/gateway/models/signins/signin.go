@@ -0,0 +1,14 @@
+package signins
+
+import "time"
+
+// SignIn is one attempt to sign in. UserID is zero when the attempt was
+// for an email that does not belong to any account.
+type SignIn struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"-"`
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Success   bool      `json:"success"`
+}
@@ -0,0 +1,17 @@
+package signins
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout is how long a MySQLStore waits for each call by default.
+const DefaultTimeout = 5 * time.Second
+
+type Store interface {
+	Insert(ctx context.Context, signIn *SignIn) (*SignIn, error)
+	// GetByUserID returns up to limit of the user's sign-ins with an ID
+	// lower than before, newest first. A before of zero starts from the
+	// most recent sign-in.
+	GetByUserID(ctx context.Context, userID int, before int64, limit int) ([]*SignIn, error)
+}
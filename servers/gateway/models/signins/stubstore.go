@@ -0,0 +1,40 @@
+package signins
+
+import (
+	"context"
+	"sync"
+)
+
+// StubStore keeps sign-ins in process memory, for tests and demos. It is
+// safe for concurrent use.
+type StubStore struct {
+	mu      sync.RWMutex
+	signIns []*SignIn
+}
+
+func NewStubStore() *StubStore {
+	return &StubStore{}
+}
+
+func (s *StubStore) Insert(ctx context.Context, signIn *SignIn) (*SignIn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inserted := *signIn
+	inserted.ID = int64(len(s.signIns) + 1)
+	s.signIns = append(s.signIns, &inserted)
+	copied := inserted
+	return &copied, nil
+}
+
+func (s *StubStore) GetByUserID(ctx context.Context, userID int, before int64, limit int) ([]*SignIn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := []*SignIn{}
+	for i := len(s.signIns) - 1; i >= 0 && len(found) < limit; i-- {
+		signIn := *s.signIns[i]
+		if signIn.UserID == userID && (before == 0 || signIn.ID < before) {
+			found = append(found, &signIn)
+		}
+	}
+	return found, nil
+}
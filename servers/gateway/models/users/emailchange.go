@@ -0,0 +1,94 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EmailChangeTTL is how long a user has to verify a new email address.
+const EmailChangeTTL = 24 * time.Hour
+
+var ErrInvalidToken = errors.New("invalid or expired verification token")
+
+// EmailChangeKeyPurpose is the purpose email change tokens derive their
+// secrets for from the gateway's keyring.
+const EmailChangeKeyPurpose = "emailchange"
+
+// EmailChange is a pending change of a user's email address. It is only
+// committed once the owner of the new address presents its token.
+type EmailChange struct {
+	UserID   int       `json:"userId"`
+	OldEmail string    `json:"oldEmail"`
+	NewEmail string    `json:"newEmail"`
+	Expires  time.Time `json:"expires"`
+}
+
+func NewEmailChange(user *User, newEmail string) *EmailChange {
+	return &EmailChange{
+		UserID:   user.ID,
+		OldEmail: user.Email,
+		NewEmail: newEmail,
+		Expires:  time.Now().Add(EmailChangeTTL),
+	}
+}
+
+func signEmailChange(payload string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Token returns the change encoded and signed with secret.
+func (ec *EmailChange) Token(secret string) (string, error) {
+	data, err := json.Marshal(ec)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signEmailChange(payload, secret), nil
+}
+
+// ParseEmailChangeToken returns the change in token, or ErrInvalidToken if
+// the token was not signed with one of secrets or has expired.
+func ParseEmailChangeToken(token string, secrets []string) (*EmailChange, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+	if !slices.ContainsFunc(secrets, func(secret string) bool {
+		return hmac.Equal([]byte(signature), []byte(signEmailChange(payload, secret)))
+	}) {
+		return nil, ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	ec := &EmailChange{}
+	err = json.Unmarshal(data, ec)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(ec.Expires) {
+		return nil, ErrInvalidToken
+	}
+	return ec, nil
+}
+
+// Apply commits the change to user. It fails if the user's email has
+// changed since the token was issued, so each token can only be used once.
+func (ec *EmailChange) Apply(user *User) error {
+	if user.ID != ec.UserID || user.Email != ec.OldEmail {
+		return ErrInvalidToken
+	}
+	user.Email = ec.NewEmail
+	user.PhotoURL = generatePhotoURL(ec.NewEmail)
+	return nil
+}
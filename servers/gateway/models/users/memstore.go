@@ -0,0 +1,257 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps users in process memory, for tests and demos. Users
+// are copied in and out, so callers never share them with the store. It
+// is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	users   map[int]*User
+	byEmail map[string]int
+	// byUsername is keyed by usernameKey, since usernames are unique
+	// whatever their case
+	byUsername map[string]int
+	nextID     int
+	index      *Trie
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:      map[int]*User{},
+		byEmail:    map[string]int{},
+		byUsername: map[string]int{},
+		nextID:     1,
+		index:      NewTrie(),
+	}
+}
+
+// usernameKey returns the key of username in byUsername.
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// checkUnique returns ErrDuplicate if a user other than the one with id
+// has the email or username of user. The caller must hold the lock.
+func (s *MemoryStore) checkUnique(id int, user *User) error {
+	if otherID, ok := s.byEmail[user.Email]; ok && otherID != id {
+		return fmt.Errorf("%w with that email", ErrDuplicate)
+	}
+	if otherID, ok := s.byUsername[usernameKey(user.Username)]; ok && otherID != id {
+		return fmt.Errorf("%w with that username", ErrDuplicate)
+	}
+	return nil
+}
+
+// put stores a copy of user, replacing the indexed fields of any earlier
+// version. The caller must hold the lock.
+func (s *MemoryStore) put(user *User) {
+	if old, ok := s.users[user.ID]; ok {
+		delete(s.byEmail, old.Email)
+		delete(s.byUsername, usernameKey(old.Username))
+	}
+	stored := *user
+	s.users[user.ID] = &stored
+	s.byEmail[user.Email] = user.ID
+	s.byUsername[usernameKey(user.Username)] = user.ID
+	s.index.Set(&stored)
+}
+
+// get returns a copy of the user with id. The caller must hold the lock.
+func (s *MemoryStore) get(id int) (*User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (s *MemoryStore) Insert(ctx context.Context, user *User) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.checkUnique(0, user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = s.nextID
+	s.nextID++
+	s.put(user)
+	return s.get(user.ID)
+}
+
+func (s *MemoryStore) GetByID(ctx context.Context, id int) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.get(id)
+}
+
+func (s *MemoryStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.byEmail[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return s.get(id)
+}
+
+func (s *MemoryStore) GetByUsername(ctx context.Context, username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.byUsername[usernameKey(username)]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return s.get(id)
+}
+
+func (s *MemoryStore) Update(ctx context.Context, id int, user *User) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return nil, ErrUserNotFound
+	}
+	err := s.checkUnique(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *user
+	updated.ID = id
+	s.put(&updated)
+	return s.get(id)
+}
+
+func (s *MemoryStore) GetByPrefix(ctx context.Context, prefix string, max int) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []*User{}
+	for _, id := range s.index.Find(prefix, max) {
+		user, err := s.get(id)
+		if err != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// snapshotUser is a user as saved in a snapshot. Unlike User, it keeps
+// every field.
+type snapshotUser struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Username  string `json:"username"`
+	PassHash  string `json:"passHash"`
+	Email     string `json:"email"`
+	PhotoURL  string `json:"photoUrl"`
+}
+
+type snapshot struct {
+	NextID int             `json:"nextId"`
+	Users  []*snapshotUser `json:"users"`
+}
+
+// Save writes a JSON snapshot of every user to w.
+func (s *MemoryStore) Save(w io.Writer) error {
+	s.mu.RLock()
+	snap := snapshot{NextID: s.nextID, Users: []*snapshotUser{}}
+	for _, user := range s.users {
+		saved := snapshotUser(*user)
+		snap.Users = append(snap.Users, &saved)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(snap.Users, func(a, b *snapshotUser) int { return a.ID - b.ID })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+// Load replaces every user with those in a JSON snapshot read from r. The
+// store is left unchanged if the snapshot is malformed or holds duplicate
+// users.
+func (s *MemoryStore) Load(r io.Reader) error {
+	snap := snapshot{}
+	err := json.NewDecoder(r).Decode(&snap)
+	if err != nil {
+		return fmt.Errorf("error decoding snapshot: %w", err)
+	}
+
+	loaded := NewMemoryStore()
+	for _, saved := range snap.Users {
+		user := User(*saved)
+		if user.ID <= 0 {
+			return fmt.Errorf("invalid user ID %d in snapshot", user.ID)
+		}
+		if _, ok := loaded.users[user.ID]; ok {
+			return fmt.Errorf("user ID %d is in the snapshot twice", user.ID)
+		}
+		err = loaded.checkUnique(user.ID, &user)
+		if err != nil {
+			return fmt.Errorf("user %d in snapshot: %w", user.ID, err)
+		}
+		loaded.put(&user)
+		loaded.nextID = max(loaded.nextID, user.ID+1)
+	}
+	loaded.nextID = max(loaded.nextID, snap.NextID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = loaded.users
+	s.byEmail = loaded.byEmail
+	s.byUsername = loaded.byUsername
+	s.nextID = loaded.nextID
+	s.index = loaded.index
+	return nil
+}
+
+// SaveFile saves a snapshot to the file at path. The snapshot is written
+// to a temporary file first, so a failed save never leaves a partial file
+// behind.
+func (s *MemoryStore) SaveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = s.Save(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile loads the snapshot in the file at path.
+func (s *MemoryStore) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
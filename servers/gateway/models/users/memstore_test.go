@@ -0,0 +1,179 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestUser(name string) *User {
+	return &User{
+		FirstName: name,
+		LastName:  "Tester",
+		Username:  name,
+		Email:     name + "@example.com",
+		PhotoURL:  "https://www.gravatar.com/avatar/" + name,
+		PassHash:  "hash-" + name,
+	}
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	user := newTestUser("alice")
+	inserted, err := store.Insert(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing users the store has handed out or been given must not
+	// change the stored user
+	user.Email = "changed@example.com"
+	inserted.Username = "changed"
+	found, _ := store.GetByID(ctx, inserted.ID)
+	found.FirstName = "Changed"
+
+	found, err = store.GetByID(ctx, inserted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := newTestUser("alice")
+	expected.ID = inserted.ID
+	if *found != *expected {
+		t.Errorf("expected %+v but got %+v", *expected, *found)
+	}
+
+	_, err = store.GetByEmail(ctx, "changed@example.com")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected the changed email not to be indexed but got %v", err)
+	}
+}
+
+func TestMemoryStoreGetByUsername(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	inserted, _ := store.Insert(ctx, newTestUser("alice"))
+
+	_, err := store.Update(ctx, inserted.ID, newTestUser("carol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.GetByUsername(ctx, "carol")
+	if err != nil || found.ID != inserted.ID {
+		t.Errorf("expected carol to be user %d but got %v, %v", inserted.ID, found, err)
+	}
+	_, err = store.GetByUsername(ctx, "alice")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected the old username to be gone but got %v", err)
+	}
+	found, err = store.GetByUsername(ctx, "Carol")
+	if err != nil || found.ID != inserted.ID {
+		t.Errorf("expected usernames to be found whatever their case but got %v, %v", found, err)
+	}
+}
+
+func TestMemoryStoreConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := store.Insert(ctx, newTestUser(fmt.Sprintf("user%d", i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for j := range 50 {
+				changes := newTestUser(fmt.Sprintf("user%d-%d", i, j))
+				store.Update(ctx, user.ID, changes)
+				store.GetByID(ctx, user.ID)
+				store.GetByEmail(ctx, changes.Email)
+				store.GetByPrefix(ctx, "user", 5)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			store.Save(&bytes.Buffer{})
+		}
+	}()
+	wg.Wait()
+}
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	alice, _ := store.Insert(ctx, newTestUser("alice"))
+	store.Insert(ctx, newTestUser("bob"))
+
+	path := filepath.Join(t.TempDir(), "users.json")
+	err := store.SaveFile(path)
+	if err != nil {
+		t.Fatalf("error saving snapshot: %s", err)
+	}
+
+	loaded := NewMemoryStore()
+	err = loaded.LoadFile(path)
+	if err != nil {
+		t.Fatalf("error loading snapshot: %s", err)
+	}
+
+	found, err := loaded.GetByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("error getting loaded user: %s", err)
+	}
+	if *found != *alice {
+		t.Errorf("expected %+v but got %+v", *alice, *found)
+	}
+	matches, _ := loaded.GetByPrefix(ctx, "bo", 10)
+	if len(matches) != 1 || matches[0].Username != "bob" {
+		t.Errorf("expected the search index to be loaded but got %v", matches)
+	}
+
+	// New users never reuse the IDs of loaded ones
+	carol, err := loaded.Insert(ctx, newTestUser("carol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if carol.ID != 3 {
+		t.Errorf("expected carol to get ID 3 but got %d", carol.ID)
+	}
+}
+
+func TestMemoryStoreLoadInvalid(t *testing.T) {
+	cases := map[string]string{
+		"malformed":          `{"users": [`,
+		"duplicate ID":       `{"users": [{"id": 1, "email": "a@example.com", "username": "a"}, {"id": 1, "email": "b@example.com", "username": "b"}]}`,
+		"duplicate email":    `{"users": [{"id": 1, "email": "a@example.com", "username": "a"}, {"id": 2, "email": "a@example.com", "username": "b"}]}`,
+		"duplicate username": `{"users": [{"id": 1, "email": "a@example.com", "username": "a"}, {"id": 2, "email": "b@example.com", "username": "a"}]}`,
+		"invalid ID":         `{"users": [{"id": 0, "email": "a@example.com", "username": "a"}]}`,
+	}
+
+	for name, snapshot := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := NewMemoryStore()
+			store.Insert(context.Background(), newTestUser("alice"))
+
+			err := store.Load(strings.NewReader(snapshot))
+			if err == nil {
+				t.Fatal("expected an error loading the snapshot")
+			}
+			_, err = store.GetByEmail(context.Background(), "alice@example.com")
+			if err != nil {
+				t.Errorf("expected the store to be unchanged but got %v", err)
+			}
+		})
+	}
+}
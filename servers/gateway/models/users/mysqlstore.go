@@ -1,17 +1,26 @@
 package users
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"messaging-application/servers/gateway/timeout"
+	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
+const errDuplicateEntry = 1062
+
 type MySQLStore struct {
-	db *sql.DB
+	db    *sql.DB
+	index *Trie
+
+	// Timeout bounds each call to the database. Zero leaves calls bounded
+	// only by their context.
+	Timeout time.Duration
 }
 
 func NewMySQLStore(db *sql.DB) (MySQLStore, error) {
@@ -19,32 +28,64 @@ func NewMySQLStore(db *sql.DB) (MySQLStore, error) {
 		return MySQLStore{}, fmt.Errorf("db must not be nil")
 	}
 
-	// Test the connection
-	for i := 1; i <= 4; i++ {
-		wait := time.Second * time.Duration(2*i)
-		time.Sleep(wait)
-		err := db.Ping()
-		if err == nil {
-			log.Print("db successfully pinged")
-			break
-		} else {
-			log.Printf("error pinging db (attempt %d): %v", i, err)
-		}
+	store := MySQLStore{db: db, index: NewTrie(), Timeout: DefaultTimeout}
+	err := store.loadIndex()
+	if err != nil {
+		return MySQLStore{}, fmt.Errorf("error loading user index: %w", err)
+	}
+
+	return store, nil
+}
+
+// duplicateError translates a unique index violation into ErrDuplicate,
+// naming the column from the index that was violated.
+func duplicateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != errDuplicateEntry {
+		return err
+	}
+
+	switch {
+	case strings.Contains(mysqlErr.Message, "idx_email"):
+		return fmt.Errorf("%w with that email", ErrDuplicate)
+	case strings.Contains(mysqlErr.Message, "idx_username"):
+		return fmt.Errorf("%w with that username", ErrDuplicate)
+	default:
+		return ErrDuplicate
 	}
+}
+
+// loadIndex adds every existing user to the in-memory search index.
+func (s *MySQLStore) loadIndex() error {
+	ctx, cancel := timeout.Context(context.Background(), indexLoadTimeout)
+	defer cancel()
 
-	err := db.Ping()
+	lq := "SELECT id, first_name, last_name, username FROM users"
+	rows, err := s.db.QueryContext(ctx, lq)
 	if err != nil {
-		return MySQLStore{}, fmt.Errorf("error pinging db: %w", err)
+		return err
 	}
+	defer rows.Close()
 
-	return MySQLStore{db: db}, nil
+	for rows.Next() {
+		user := User{}
+		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username)
+		if err != nil {
+			return err
+		}
+		s.index.Set(&user)
+	}
+	return rows.Err()
 }
 
-func (s *MySQLStore) Insert(user *User) (*User, error) {
+func (s *MySQLStore) Insert(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
 	insq := "INSERT INTO users(first_name, last_name, username, email, photo_url, pass_hash) VALUES(?,?,?,?,?,?)"
-	res, err := s.db.Exec(insq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash)
+	res, err := s.db.ExecContext(ctx, insq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash)
 	if err != nil {
-		return nil, err
+		return nil, duplicateError(err)
 	}
 
 	id, err := res.LastInsertId()
@@ -53,20 +94,28 @@ func (s *MySQLStore) Insert(user *User) (*User, error) {
 	}
 
 	user.ID = int(id)
+	s.index.Set(user)
 	return user, nil
 }
 
-func (s *MySQLStore) GetByID(id int) (*User, error) {
+func (s *MySQLStore) GetByID(ctx context.Context, id int) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
 	gq := "SELECT id, first_name, last_name, username, email, photo_url, pass_hash FROM users where id = ?"
-	rows, err := s.db.Query(gq, id)
+	rows, err := s.db.QueryContext(ctx, gq, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	user := User{}
 	found := rows.Next()
 	if !found {
-		return nil, errors.New("user was not found")
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrUserNotFound
 	}
 
 	err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username, &user.Email, &user.PhotoURL, &user.PassHash)
@@ -77,17 +126,24 @@ func (s *MySQLStore) GetByID(id int) (*User, error) {
 	return &user, nil
 }
 
-func (s *MySQLStore) GetByEmail(email string) (*User, error) {
+func (s *MySQLStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
 	gq := "SELECT id, first_name, last_name, username, email, photo_url, pass_hash FROM users where email = ?"
-	rows, err := s.db.Query(gq, email)
+	rows, err := s.db.QueryContext(ctx, gq, email)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	user := User{}
 	found := rows.Next()
 	if !found {
-		return nil, errors.New("user was not found")
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrUserNotFound
 	}
 
 	err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username, &user.Email, &user.PhotoURL, &user.PassHash)
@@ -98,17 +154,66 @@ func (s *MySQLStore) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (s *MySQLStore) Update(id int, user *User) (*User, error) {
+func (s *MySQLStore) Update(ctx context.Context, id int, user *User) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
 	uq := "UPDATE users SET first_name = ?, last_name = ?, username = ?, email = ?, photo_url = ?, pass_hash = ? WHERE id = ?"
-	_, err := s.db.Exec(uq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash, id)
+	_, err := s.db.ExecContext(ctx, uq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash, id)
 	if err != nil {
-		return nil, err
+		return nil, duplicateError(err)
 	}
 
-	updatedUser, err := s.GetByID(id)
+	updatedUser, err := s.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	s.index.Set(updatedUser)
 	return updatedUser, nil
 }
+
+func (s *MySQLStore) GetByPrefix(ctx context.Context, prefix string, max int) ([]*User, error) {
+	ids := s.index.Find(prefix, max)
+	if len(ids) == 0 {
+		return []*User{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	gq := "SELECT id, first_name, last_name, username, email, photo_url, pass_hash FROM users WHERE id IN (" + placeholders + ")"
+	rows, err := s.db.QueryContext(ctx, gq, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[int]*User{}
+	for rows.Next() {
+		user := User{}
+		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username, &user.Email, &user.PhotoURL, &user.PassHash)
+		if err != nil {
+			return nil, err
+		}
+		found[user.ID] = &user
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep the order chosen by the index
+	users := []*User{}
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
@@ -1,12 +1,17 @@
 package users
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 )
 
+var ctx = context.Background()
+
 var u *User = &User{
 	FirstName: "Bob",
 	LastName:  "McDonald",
@@ -32,8 +37,8 @@ func TestShouldInsertNewUser(t *testing.T) {
 
 	mock.ExpectExec("INSERT INTO users").WithArgs(u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash).WillReturnResult(sqlmock.NewResult(1, 1))
 
-	store := MySQLStore{db: db}
-	newUser, err := store.Insert(u)
+	store := MySQLStore{db: db, index: NewTrie()}
+	newUser, err := store.Insert(ctx, u)
 	if err != nil {
 		t.Errorf("Error inserting user: %s", err)
 	}
@@ -52,12 +57,12 @@ func TestShouldIncrementUserID(t *testing.T) {
 	mock.ExpectExec("INSERT INTO users").WithArgs(u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("INSERT INTO users").WithArgs(u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash).WillReturnResult(sqlmock.NewResult(2, 1))
 
-	store := MySQLStore{db: db}
-	_, err := store.Insert(u)
+	store := MySQLStore{db: db, index: NewTrie()}
+	_, err := store.Insert(ctx, u)
 	if err != nil {
 		t.Errorf("Error inserting user: %s", err)
 	}
-	newUser, err := store.Insert(u)
+	newUser, err := store.Insert(ctx, u)
 	if err != nil {
 		t.Errorf("Error inserting user: %s", err)
 	}
@@ -75,8 +80,8 @@ func TestShouldReturnInsertionError(t *testing.T) {
 
 	mock.ExpectExec("INSERT INTO users").WithArgs(u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash).WillReturnError(errors.New(""))
 
-	store := MySQLStore{db: db}
-	_, err := store.Insert(u)
+	store := MySQLStore{db: db, index: NewTrie()}
+	_, err := store.Insert(ctx, u)
 	if err == nil {
 		t.Errorf("Expected insertion error")
 	}
@@ -85,6 +90,27 @@ func TestShouldReturnInsertionError(t *testing.T) {
 	}
 }
 
+func TestShouldReturnDuplicateError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO users").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Bobby99' for key 'users.idx_username'"})
+	mock.ExpectExec("UPDATE").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'bobby@gmail.com' for key 'users.idx_email'"})
+
+	store := MySQLStore{db: db, index: NewTrie()}
+	_, err := store.Insert(ctx, u)
+	if !errors.Is(err, ErrDuplicate) || err.Error() != "user already exists with that username" {
+		t.Errorf("Expected duplicate username error but got %v", err)
+	}
+	_, err = store.Update(ctx, 1, u)
+	if !errors.Is(err, ErrDuplicate) || err.Error() != "user already exists with that email" {
+		t.Errorf("Expected duplicate email error but got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
 func TestShouldSelectUserByID(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
@@ -94,8 +120,8 @@ func TestShouldSelectUserByID(t *testing.T) {
 
 	mock.ExpectQuery("SELECT").WithArgs(uWithID.ID).WillReturnRows(data)
 
-	store := MySQLStore{db: db}
-	newUser, err := store.GetByID(1)
+	store := MySQLStore{db: db, index: NewTrie()}
+	newUser, err := store.GetByID(ctx, 1)
 	if err != nil {
 		t.Errorf("Error fetching user from database: %s", err)
 	}
@@ -114,8 +140,8 @@ func TestShouldHandleMissingUser(t *testing.T) {
 	data := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"})
 	mock.ExpectQuery("SELECT").WithArgs(uWithID.ID).WillReturnRows(data)
 
-	store := MySQLStore{db: db}
-	_, err := store.GetByID(1)
+	store := MySQLStore{db: db, index: NewTrie()}
+	_, err := store.GetByID(ctx, 1)
 	if err == nil {
 		t.Errorf("Expected Get operation to return a not found error")
 	}
@@ -124,6 +150,20 @@ func TestShouldHandleMissingUser(t *testing.T) {
 	}
 }
 
+func TestShouldTimeOutLookup(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	data := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"})
+	mock.ExpectQuery("SELECT").WithArgs(uWithID.ID).WillReturnRows(data).WillDelayFor(time.Second)
+
+	store := MySQLStore{db: db, index: NewTrie(), Timeout: 10 * time.Millisecond}
+	_, err := store.GetByID(ctx, 1)
+	if err == nil || errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected the lookup to be cancelled but got %v", err)
+	}
+}
+
 func TestShouldUpdateUser(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
@@ -134,8 +174,8 @@ func TestShouldUpdateUser(t *testing.T) {
 	data.AddRow(uWithID.ID, u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash)
 	mock.ExpectQuery("SELECT").WithArgs(uWithID.ID).WillReturnRows(data)
 
-	store := MySQLStore{db: db}
-	updatedUser, err := store.Update(1, u)
+	store := MySQLStore{db: db, index: NewTrie()}
+	updatedUser, err := store.Update(ctx, 1, u)
 	if err != nil {
 		t.Errorf("Error updating user: %s", err)
 	}
@@ -146,3 +186,93 @@ func TestShouldUpdateUser(t *testing.T) {
 		t.Errorf("Expectations were not met: %s", err)
 	}
 }
+
+func TestShouldLoadIndex(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	data := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username"})
+	data.AddRow(1, "Bob", "McDonald", "Bobby99")
+	data.AddRow(2, "Mary", "Bobson", "mary")
+	mock.ExpectQuery("SELECT id, first_name, last_name, username FROM users").WillReturnRows(data)
+
+	store := MySQLStore{db: db, index: NewTrie()}
+	err := store.loadIndex()
+	if err != nil {
+		t.Errorf("Error loading index: %s", err)
+	}
+	if ids := store.index.Find("bob", 20); len(ids) != 2 {
+		t.Errorf("Expected 2 users to match but got %v", ids)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldSelectUsersByPrefix(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := MySQLStore{db: db, index: NewTrie()}
+	store.index.Set(&User{ID: 1, FirstName: "Bob", LastName: "McDonald", Username: "Bobby99"})
+	store.index.Set(&User{ID: 2, FirstName: "Alice", LastName: "Bobson", Username: "alice"})
+	store.index.Set(&User{ID: 3, FirstName: "Carol", Username: "carol"})
+
+	// Rows come back in ID order but results keep the index order
+	data := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"})
+	data.AddRow(1, "Bob", "McDonald", "Bobby99", u.Email, u.PhotoURL, u.PassHash)
+	data.AddRow(2, "Alice", "Bobson", "alice", u.Email, u.PhotoURL, u.PassHash)
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id IN").WithArgs(1, 2).WillReturnRows(data)
+
+	found, err := store.GetByPrefix(ctx, "bob", 20)
+	if err != nil {
+		t.Errorf("Error searching users: %s", err)
+	}
+	if len(found) != 2 || found[0].ID != 1 || found[1].ID != 2 {
+		t.Errorf("Expected users 1 and 2 but got %v", found)
+	}
+
+	// No matches means no query
+	found, err = store.GetByPrefix(ctx, "zed", 20)
+	if err != nil || len(found) != 0 {
+		t.Errorf("Expected no users and no error but got %v and %v", found, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldIndexInsertedAndUpdatedUsers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(1, 1))
+	data := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"})
+	data.AddRow(1, "Robert", u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash)
+	mock.ExpectQuery("SELECT").WithArgs(1).WillReturnRows(data)
+
+	store := MySQLStore{db: db, index: NewTrie()}
+	inserted, err := store.Insert(ctx, &User{FirstName: "Bob", LastName: u.LastName, Username: u.Username})
+	if err != nil {
+		t.Fatalf("Error inserting user: %s", err)
+	}
+	if ids := store.index.Find("bob", 20); len(ids) != 1 {
+		t.Errorf("Expected inserted user to be indexed but got %v", ids)
+	}
+
+	inserted.FirstName = "Robert"
+	_, err = store.Update(ctx, 1, inserted)
+	if err != nil {
+		t.Fatalf("Error updating user: %s", err)
+	}
+	if ids := store.index.Find("rob", 20); len(ids) != 1 {
+		t.Errorf("Expected updated user to be indexed but got %v", ids)
+	}
+	if ids := store.index.Find("bob", 20); len(ids) != 1 {
+		t.Errorf("Expected username to stay indexed but got %v", ids)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
@@ -0,0 +1,188 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"messaging-application/servers/gateway/timeout"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errUniqueViolation is the Postgres error code for a unique index
+// violation.
+const errUniqueViolation = "23505"
+
+type PostgresStore struct {
+	db    *sql.DB
+	index *Trie
+
+	// Timeout bounds each call to the database. Zero leaves calls bounded
+	// only by their context.
+	Timeout time.Duration
+}
+
+func NewPostgresStore(db *sql.DB) (PostgresStore, error) {
+	if db == nil {
+		return PostgresStore{}, fmt.Errorf("db must not be nil")
+	}
+
+	store := PostgresStore{db: db, index: NewTrie(), Timeout: DefaultTimeout}
+	err := store.loadIndex()
+	if err != nil {
+		return PostgresStore{}, fmt.Errorf("error loading user index: %w", err)
+	}
+
+	return store, nil
+}
+
+// pgDuplicateError translates a unique index violation into ErrDuplicate,
+// naming the column from the index that was violated.
+func pgDuplicateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != errUniqueViolation {
+		return err
+	}
+
+	switch pqErr.Constraint {
+	case "idx_email":
+		return fmt.Errorf("%w with that email", ErrDuplicate)
+	case "idx_username":
+		return fmt.Errorf("%w with that username", ErrDuplicate)
+	default:
+		return ErrDuplicate
+	}
+}
+
+// loadIndex adds every existing user to the in-memory search index.
+func (s *PostgresStore) loadIndex() error {
+	ctx, cancel := timeout.Context(context.Background(), indexLoadTimeout)
+	defer cancel()
+
+	lq := "SELECT id, first_name, last_name, username FROM users"
+	rows, err := s.db.QueryContext(ctx, lq)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := User{}
+		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username)
+		if err != nil {
+			return err
+		}
+		s.index.Set(&user)
+	}
+	return rows.Err()
+}
+
+func (s *PostgresStore) Insert(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	insq := "INSERT INTO users (first_name, last_name, username, email, photo_url, pass_hash) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	var id int
+	err := s.db.QueryRowContext(ctx, insq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash).Scan(&id)
+	if err != nil {
+		return nil, pgDuplicateError(err)
+	}
+
+	user.ID = id
+	s.index.Set(user)
+	return user, nil
+}
+
+// getBy returns the user whose column equals value.
+func (s *PostgresStore) getBy(ctx context.Context, column string, value any) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	gq := "SELECT id, first_name, last_name, username, email, photo_url, pass_hash FROM users WHERE " + column + " = $1"
+	user := User{}
+	err := s.db.QueryRowContext(ctx, gq, value).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username, &user.Email, &user.PhotoURL, &user.PassHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *PostgresStore) GetByID(ctx context.Context, id int) (*User, error) {
+	return s.getBy(ctx, "id", id)
+}
+
+func (s *PostgresStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return s.getBy(ctx, "email", email)
+}
+
+func (s *PostgresStore) Update(ctx context.Context, id int, user *User) (*User, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	uq := "UPDATE users SET first_name = $1, last_name = $2, username = $3, email = $4, photo_url = $5, pass_hash = $6 WHERE id = $7 " +
+		"RETURNING id, first_name, last_name, username, email, photo_url, pass_hash"
+	updatedUser := User{}
+	err := s.db.QueryRowContext(ctx, uq, user.FirstName, user.LastName, user.Username, user.Email, user.PhotoURL, user.PassHash, id).
+		Scan(&updatedUser.ID, &updatedUser.FirstName, &updatedUser.LastName, &updatedUser.Username, &updatedUser.Email, &updatedUser.PhotoURL, &updatedUser.PassHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, pgDuplicateError(err)
+	}
+
+	s.index.Set(&updatedUser)
+	return &updatedUser, nil
+}
+
+func (s *PostgresStore) GetByPrefix(ctx context.Context, prefix string, max int) ([]*User, error) {
+	ids := s.index.Find(prefix, max)
+	if len(ids) == 0 {
+		return []*User{}, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	gq := "SELECT id, first_name, last_name, username, email, photo_url, pass_hash FROM users WHERE id IN (" + strings.Join(placeholders, ",") + ")"
+	rows, err := s.db.QueryContext(ctx, gq, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[int]*User{}
+	for rows.Next() {
+		user := User{}
+		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Username, &user.Email, &user.PhotoURL, &user.PassHash)
+		if err != nil {
+			return nil, err
+		}
+		found[user.ID] = &user
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep the order chosen by the index
+	users := []*User{}
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
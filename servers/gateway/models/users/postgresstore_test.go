@@ -0,0 +1,100 @@
+package users
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestPostgresInsertReturnsID(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO users (.+) RETURNING id`).
+		WithArgs(u.FirstName, u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	store := PostgresStore{db: db, index: NewTrie()}
+	inserted, err := store.Insert(ctx, &User{FirstName: u.FirstName, LastName: u.LastName, Username: u.Username, Email: u.Email, PhotoURL: u.PhotoURL, PassHash: u.PassHash})
+	if err != nil {
+		t.Fatalf("Error inserting user: %s", err)
+	}
+	if inserted.ID != 7 {
+		t.Errorf("Expected ID 7 but got %d", inserted.ID)
+	}
+	if ids := store.index.Find("bob", 20); len(ids) != 1 {
+		t.Errorf("Expected the new user to be indexed but found %v", ids)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestPostgresDuplicates(t *testing.T) {
+	cases := map[string]string{
+		"idx_email":    "user already exists with that email",
+		"idx_username": "user already exists with that username",
+	}
+
+	for constraint, message := range cases {
+		db, mock, _ := sqlmock.New()
+
+		mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: errUniqueViolation, Constraint: constraint})
+
+		store := PostgresStore{db: db, index: NewTrie()}
+		_, err := store.Insert(ctx, &User{Username: "bob"})
+		if !errors.Is(err, ErrDuplicate) || err.Error() != message {
+			t.Errorf("Expected %q for %s but got %v", message, constraint, err)
+		}
+		db.Close()
+	}
+}
+
+func TestPostgresGetMissing(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).WithArgs("nobody@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"}))
+
+	store := PostgresStore{db: db, index: NewTrie()}
+	_, err := store.GetByEmail(ctx, "nobody@example.com")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound but got %v", err)
+	}
+}
+
+func TestPostgresUpdate(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "photo_url", "pass_hash"}).
+		AddRow(1, "Rob", u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash)
+	mock.ExpectQuery(`UPDATE users SET (.+) WHERE id = \$7 RETURNING`).
+		WithArgs("Rob", u.LastName, u.Username, u.Email, u.PhotoURL, u.PassHash, 1).
+		WillReturnRows(rows)
+	mock.ExpectQuery("UPDATE users").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	store := PostgresStore{db: db, index: NewTrie()}
+	store.index.Set(uWithID)
+	updated, err := store.Update(ctx, 1, &User{FirstName: "Rob", LastName: u.LastName, Username: u.Username, Email: u.Email, PhotoURL: u.PhotoURL, PassHash: u.PassHash})
+	if err != nil {
+		t.Fatalf("Error updating user: %s", err)
+	}
+	if updated.FirstName != "Rob" {
+		t.Errorf("Expected the first name to be updated but got %s", updated.FirstName)
+	}
+	if ids := store.index.Find("rob", 20); len(ids) != 1 {
+		t.Errorf("Expected the new name to be indexed but found %v", ids)
+	}
+
+	_, err = store.Update(ctx, 2, &User{})
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for a missing user but got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
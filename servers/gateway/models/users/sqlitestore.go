@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStore keeps users in a SQLite database. SQLite accepts the same
+// queries as MySQL, so it is a MySQLStore that only differs in how
+// duplicates are reported.
+type SQLiteStore struct {
+	MySQLStore
+}
+
+func NewSQLiteStore(db *sql.DB) (SQLiteStore, error) {
+	store, err := NewMySQLStore(db)
+	if err != nil {
+		return SQLiteStore{}, err
+	}
+	return SQLiteStore{MySQLStore: store}, nil
+}
+
+// sqliteDuplicateError translates a unique index violation into
+// ErrDuplicate, naming the column that was violated.
+func sqliteDuplicateError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+
+	switch {
+	case strings.Contains(sqliteErr.Error(), "users.email"):
+		return fmt.Errorf("%w with that email", ErrDuplicate)
+	case strings.Contains(sqliteErr.Error(), "idx_username"):
+		return fmt.Errorf("%w with that username", ErrDuplicate)
+	default:
+		return ErrDuplicate
+	}
+}
+
+func (s *SQLiteStore) Insert(ctx context.Context, user *User) (*User, error) {
+	inserted, err := s.MySQLStore.Insert(ctx, user)
+	if err != nil {
+		return nil, sqliteDuplicateError(err)
+	}
+	return inserted, nil
+}
+
+func (s *SQLiteStore) Update(ctx context.Context, id int, user *User) (*User, error) {
+	updated, err := s.MySQLStore.Update(ctx, id, user)
+	if err != nil {
+		return nil, sqliteDuplicateError(err)
+	}
+	return updated, nil
+}
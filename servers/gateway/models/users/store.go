@@ -1,8 +1,31 @@
 package users
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUserNotFound is returned when no user matches a lookup.
+var ErrUserNotFound = errors.New("user was not found")
+
+// ErrDuplicate is returned when inserting or updating a user would reuse
+// another user's email or username. Usernames that differ only by case
+// are the same username in every store.
+var ErrDuplicate = errors.New("user already exists")
+
+// DefaultTimeout is how long a MySQLStore waits for each call by default.
+const DefaultTimeout = 5 * time.Second
+
+// indexLoadTimeout bounds reading every user into the search index when a
+// store is created. It is longer than DefaultTimeout since it reads the
+// whole table.
+const indexLoadTimeout = time.Minute
+
 type Store interface {
-	Insert(user *User) (*User, error)
-	GetByID(id int) (*User, error)
-	GetByEmail(email string) (*User, error)
-	Update(id int, user *User) (*User, error)
+	Insert(ctx context.Context, user *User) (*User, error)
+	GetByID(ctx context.Context, id int) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, id int, user *User) (*User, error)
+	GetByPrefix(ctx context.Context, prefix string, max int) ([]*User, error)
 }
@@ -0,0 +1,77 @@
+//go:build !no_db
+
+package users_test
+
+import (
+	"context"
+	"database/sql"
+	"messaging-application/servers/gateway/migrations"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/models/users/storetest"
+	"os"
+	"testing"
+)
+
+// openTestDB connects to the database in the environment variable env and
+// brings its schema up to date. The test is skipped when env is not set,
+// since the database is emptied before every test.
+func openTestDB(t *testing.T, env string, driver string, dialect migrations.Dialect) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("Error opening db: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	all, err := migrations.Embedded(dialect)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %s", err)
+	}
+	migrator, err := migrations.NewMigrator(db, dialect, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Error migrating test db: %s", err)
+	}
+	return db
+}
+
+// emptyTables deletes every row that refers to a user, and the users.
+func emptyTables(t *testing.T, db *sql.DB) {
+	for _, table := range []string{"user_signins", "messages", "channel_members", "channels", "users"} {
+		_, err := db.Exec("DELETE FROM " + table)
+		if err != nil {
+			t.Fatalf("Error emptying %s: %s", table, err)
+		}
+	}
+}
+
+func TestMySQLStoreConformance(t *testing.T) {
+	db := openTestDB(t, "MYSQLTESTDSN", "mysql", migrations.MySQL)
+	storetest.Run(t, func(t *testing.T) users.Store {
+		emptyTables(t, db)
+		store, err := users.NewMySQLStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &store
+	})
+}
+
+func TestPostgresStoreConformance(t *testing.T) {
+	db := openTestDB(t, "POSTGRESTESTDSN", "postgres", migrations.Postgres)
+	storetest.Run(t, func(t *testing.T) users.Store {
+		emptyTables(t, db)
+		store, err := users.NewPostgresStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &store
+	})
+}
@@ -0,0 +1,48 @@
+package users_test
+
+import (
+	"context"
+	"database/sql"
+	"messaging-application/servers/gateway/migrations"
+	"messaging-application/servers/gateway/models/users"
+	"messaging-application/servers/gateway/models/users/storetest"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) users.Store {
+		return users.NewMemoryStore()
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) users.Store {
+		db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "gateway.db"))
+		if err != nil {
+			t.Fatalf("Error opening db: %s", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		all, err := migrations.Embedded(migrations.SQLite)
+		if err != nil {
+			t.Fatalf("Error loading migrations: %s", err)
+		}
+		migrator, err := migrations.NewMigrator(db, migrations.SQLite, all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = migrator.Up(context.Background())
+		if err != nil {
+			t.Fatalf("Error migrating db: %s", err)
+		}
+
+		store, err := users.NewSQLiteStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &store
+	})
+}
@@ -0,0 +1,295 @@
+// Package storetest is a conformance test suite for implementations of
+// users.Store, so that every store behaves the same way to the handlers.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messaging-application/servers/gateway/models/users"
+	"testing"
+)
+
+// Run runs every conformance test against stores made by newStore, which
+// must return a new, empty store each time it is called.
+func Run(t *testing.T, newStore func(t *testing.T) users.Store) {
+	tests := map[string]func(t *testing.T, store users.Store){
+		"InsertAssignsIDs":        testInsertAssignsIDs,
+		"GetMissing":              testGetMissing,
+		"InsertDuplicate":         testInsertDuplicate,
+		"Update":                  testUpdate,
+		"UpdateMissing":           testUpdateMissing,
+		"UpdateDuplicate":         testUpdateDuplicate,
+		"UsernameCase":            testUsernameCase,
+		"GetByPrefix":             testGetByPrefix,
+		"GetByPrefixAfterUpdates": testGetByPrefixAfterUpdates,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+// newUser returns a user whose unique fields are derived from name.
+func newUser(name string) *users.User {
+	return &users.User{
+		FirstName: name,
+		LastName:  "Tester",
+		Username:  name,
+		Email:     fmt.Sprintf("%s@example.com", name),
+		PhotoURL:  "https://www.gravatar.com/avatar/" + name,
+		PassHash:  "hash-" + name,
+	}
+}
+
+// insert adds the users to store, failing the test on any error.
+func insert(t *testing.T, store users.Store, toInsert ...*users.User) []*users.User {
+	t.Helper()
+	inserted := []*users.User{}
+	for _, user := range toInsert {
+		added, err := store.Insert(context.Background(), user)
+		if err != nil {
+			t.Fatalf("Error inserting %s: %s", user.Username, err)
+		}
+		inserted = append(inserted, added)
+	}
+	return inserted
+}
+
+// checkUser fails the test if got does not have every field of expected.
+func checkUser(t *testing.T, got *users.User, expected *users.User) {
+	t.Helper()
+	if *got != *expected {
+		t.Errorf("Expected user %+v but got %+v", *expected, *got)
+	}
+}
+
+func testInsertAssignsIDs(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	alice := *newUser("alice")
+	bob := *newUser("bob")
+	inserted := insert(t, store, newUser("alice"), newUser("bob"))
+
+	if inserted[0].ID == 0 || inserted[0].ID == inserted[1].ID {
+		t.Fatalf("Expected distinct IDs but got %d and %d", inserted[0].ID, inserted[1].ID)
+	}
+	alice.ID = inserted[0].ID
+	bob.ID = inserted[1].ID
+
+	found, err := store.GetByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Error getting user by ID: %s", err)
+	}
+	checkUser(t, found, &alice)
+
+	found, err = store.GetByEmail(ctx, bob.Email)
+	if err != nil {
+		t.Fatalf("Error getting user by email: %s", err)
+	}
+	checkUser(t, found, &bob)
+}
+
+func testGetMissing(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"))
+
+	_, err := store.GetByID(ctx, inserted[0].ID+1000)
+	if !errors.Is(err, users.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for a missing ID but got %v", err)
+	}
+	_, err = store.GetByEmail(ctx, "nobody@example.com")
+	if !errors.Is(err, users.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for a missing email but got %v", err)
+	}
+}
+
+func testInsertDuplicate(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	insert(t, store, newUser("alice"))
+
+	sameEmail := newUser("other")
+	sameEmail.Email = "alice@example.com"
+	_, err := store.Insert(ctx, sameEmail)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a reused email but got %v", err)
+	}
+
+	sameUsername := newUser("other")
+	sameUsername.Username = "alice"
+	_, err = store.Insert(ctx, sameUsername)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a reused username but got %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"))
+	id := inserted[0].ID
+
+	changes := &users.User{
+		FirstName: "Alicia",
+		LastName:  "Changed",
+		Username:  "alicia",
+		Email:     "alicia@example.com",
+		PhotoURL:  "https://www.gravatar.com/avatar/alicia",
+		PassHash:  "new-hash",
+	}
+	updated, err := store.Update(ctx, id, changes)
+	if err != nil {
+		t.Fatalf("Error updating user: %s", err)
+	}
+
+	expected := *changes
+	expected.ID = id
+	checkUser(t, updated, &expected)
+
+	found, err := store.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Error getting updated user: %s", err)
+	}
+	checkUser(t, found, &expected)
+
+	_, err = store.GetByEmail(ctx, "alice@example.com")
+	if !errors.Is(err, users.ErrUserNotFound) {
+		t.Errorf("Expected the old email to be gone but got %v", err)
+	}
+}
+
+func testUpdateMissing(t *testing.T, store users.Store) {
+	inserted := insert(t, store, newUser("alice"))
+
+	_, err := store.Update(context.Background(), inserted[0].ID+1000, newUser("bob"))
+	if !errors.Is(err, users.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound but got %v", err)
+	}
+}
+
+func testUpdateDuplicate(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"), newUser("bob"))
+	id := inserted[1].ID
+
+	sameEmail := newUser("bob")
+	sameEmail.Email = "alice@example.com"
+	_, err := store.Update(ctx, id, sameEmail)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a reused email but got %v", err)
+	}
+
+	sameUsername := newUser("bob")
+	sameUsername.Username = "alice"
+	_, err = store.Update(ctx, id, sameUsername)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a reused username but got %v", err)
+	}
+
+	found, err := store.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Error getting user: %s", err)
+	}
+	expected := newUser("bob")
+	expected.ID = id
+	checkUser(t, found, expected)
+}
+
+// testUsernameCase checks that usernames differing only by case collide,
+// while a user can still change the case of their own username.
+func testUsernameCase(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"), newUser("bob"))
+
+	shouting := newUser("other")
+	shouting.Username = "ALICE"
+	_, err := store.Insert(ctx, shouting)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a username differing only by case but got %v", err)
+	}
+
+	renamed := newUser("bob")
+	renamed.Username = "Alice"
+	_, err = store.Update(ctx, inserted[1].ID, renamed)
+	if !errors.Is(err, users.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for updating to a username differing only by case but got %v", err)
+	}
+
+	recased := newUser("alice")
+	recased.Username = "Alice"
+	updated, err := store.Update(ctx, inserted[0].ID, recased)
+	if err != nil {
+		t.Fatalf("Error changing the case of a username: %s", err)
+	}
+	if updated.Username != "Alice" {
+		t.Errorf("Expected the username to keep its case but got %s", updated.Username)
+	}
+}
+
+func testGetByPrefix(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"), newUser("albert"), newUser("bob"))
+
+	found, err := store.GetByPrefix(ctx, "AL", 10)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 users but got %d", len(found))
+	}
+	// Matches are ordered by the matching name
+	if found[0].ID != inserted[1].ID || found[1].ID != inserted[0].ID {
+		t.Errorf("Expected albert then alice but got %s then %s", found[0].Username, found[1].Username)
+	}
+
+	found, err = store.GetByPrefix(ctx, "al", 1)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if len(found) != 1 {
+		t.Errorf("Expected the limit of 1 user but got %d", len(found))
+	}
+
+	found, err = store.GetByPrefix(ctx, "tester", 10)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected every user to match their last name but got %d", len(found))
+	}
+
+	found, err = store.GetByPrefix(ctx, "zed", 10)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if found == nil || len(found) != 0 {
+		t.Errorf("Expected an empty result but got %v", found)
+	}
+}
+
+func testGetByPrefixAfterUpdates(t *testing.T, store users.Store) {
+	ctx := context.Background()
+	inserted := insert(t, store, newUser("alice"))
+
+	changes := newUser("carol")
+	_, err := store.Update(ctx, inserted[0].ID, changes)
+	if err != nil {
+		t.Fatalf("Error updating user: %s", err)
+	}
+
+	found, err := store.GetByPrefix(ctx, "alice", 10)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Expected the old name to no longer match but got %d users", len(found))
+	}
+
+	found, err = store.GetByPrefix(ctx, "car", 10)
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+	if len(found) != 1 || found[0].Username != "carol" {
+		t.Errorf("Expected the new name to match but got %v", found)
+	}
+}
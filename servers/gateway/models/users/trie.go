@@ -0,0 +1,147 @@
+package users
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+type trieNode struct {
+	children map[rune]*trieNode
+	ids      map[int]struct{}
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{
+		children: map[rune]*trieNode{},
+		ids:      map[int]struct{}{},
+	}
+}
+
+// Trie is a case-insensitive prefix index from the words of user names to
+// user IDs. It is safe for concurrent use.
+type Trie struct {
+	mu   sync.RWMutex
+	root *trieNode
+	keys map[int][]string
+}
+
+func NewTrie() *Trie {
+	return &Trie{
+		root: newTrieNode(),
+		keys: map[int][]string{},
+	}
+}
+
+// indexKeys returns the lowercased words of the user's username, first
+// name and last name.
+func indexKeys(user *User) []string {
+	keys := []string{}
+	for _, field := range []string{user.Username, user.FirstName, user.LastName} {
+		for _, word := range strings.Fields(strings.ToLower(field)) {
+			if !slices.Contains(keys, word) {
+				keys = append(keys, word)
+			}
+		}
+	}
+	return keys
+}
+
+// Set indexes the user under their current names, replacing whatever
+// was indexed for the same ID before.
+func (t *Trie) Set(user *User) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remove(user.ID)
+	keys := indexKeys(user)
+	for _, key := range keys {
+		node := t.root
+		for _, r := range key {
+			child, ok := node.children[r]
+			if !ok {
+				child = newTrieNode()
+				node.children[r] = child
+			}
+			node = child
+		}
+		node.ids[user.ID] = struct{}{}
+	}
+	t.keys[user.ID] = keys
+}
+
+// Delete removes the user with the given ID from the index.
+func (t *Trie) Delete(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(id)
+}
+
+func (t *Trie) remove(id int) {
+	for _, key := range t.keys[id] {
+		t.removeKey(t.root, []rune(key), id)
+	}
+	delete(t.keys, id)
+}
+
+// removeKey deletes id from the node at the end of key, pruning nodes
+// left empty. It reports whether node itself is now empty.
+func (t *Trie) removeKey(node *trieNode, key []rune, id int) bool {
+	if len(key) == 0 {
+		delete(node.ids, id)
+	} else if child, ok := node.children[key[0]]; ok && t.removeKey(child, key[1:], id) {
+		delete(node.children, key[0])
+	}
+	return len(node.ids) == 0 && len(node.children) == 0
+}
+
+// Find returns up to max IDs of users with a name that starts with
+// prefix, ordered by the matching name and then by ID.
+func (t *Trie) Find(prefix string, max int) []int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node := t.root
+	for _, r := range strings.ToLower(strings.TrimSpace(prefix)) {
+		child, ok := node.children[r]
+		if !ok {
+			return []int{}
+		}
+		node = child
+	}
+
+	ids := []int{}
+	seen := map[int]struct{}{}
+	var collect func(node *trieNode)
+	collect = func(node *trieNode) {
+		matches := []int{}
+		for id := range node.ids {
+			if _, ok := seen[id]; !ok {
+				matches = append(matches, id)
+			}
+		}
+		slices.Sort(matches)
+		for _, id := range matches {
+			if len(ids) == max {
+				return
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+
+		runes := []rune{}
+		for r := range node.children {
+			runes = append(runes, r)
+		}
+		slices.Sort(runes)
+		for _, r := range runes {
+			if len(ids) == max {
+				return
+			}
+			collect(node.children[r])
+		}
+	}
+	collect(node)
+
+	return ids
+}
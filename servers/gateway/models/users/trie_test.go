@@ -0,0 +1,56 @@
+package users
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTrieFind(t *testing.T) {
+	trie := NewTrie()
+	trie.Set(&User{ID: 1, Username: "bobby99", FirstName: "Bob", LastName: "McDonald"})
+	trie.Set(&User{ID: 2, Username: "mary", FirstName: "Mary Ann", LastName: "Bobson"})
+	trie.Set(&User{ID: 3, Username: "alice", FirstName: "Alice", LastName: "Smith"})
+
+	cases := []struct {
+		prefix string
+		max    int
+		output []int
+	}{
+		{"bob", 20, []int{1, 2}},
+		{"BOB", 20, []int{1, 2}},
+		{"bobs", 20, []int{2}},
+		{"ann", 20, []int{2}},
+		{"m", 20, []int{2, 1}},
+		{"m", 1, []int{2}},
+		{"z", 20, []int{}},
+		{"", 20, []int{3, 2, 1}},
+	}
+
+	for _, c := range cases {
+		ids := trie.Find(c.prefix, c.max)
+		if !slices.Equal(ids, c.output) {
+			t.Errorf("Find(%q, %d): expected %v but got %v", c.prefix, c.max, c.output, ids)
+		}
+	}
+}
+
+func TestTrieSetReplaces(t *testing.T) {
+	trie := NewTrie()
+	trie.Set(&User{ID: 1, Username: "bobby99", FirstName: "Bob"})
+	trie.Set(&User{ID: 1, Username: "robert", FirstName: "Robert"})
+
+	if ids := trie.Find("bob", 20); len(ids) != 0 {
+		t.Errorf("Expected old names to be removed from the index but got %v", ids)
+	}
+	if ids := trie.Find("rob", 20); !slices.Equal(ids, []int{1}) {
+		t.Errorf("Expected new names to be indexed but got %v", ids)
+	}
+
+	trie.Delete(1)
+	if ids := trie.Find("", 20); len(ids) != 0 {
+		t.Errorf("Expected deleted user to be removed from the index but got %v", ids)
+	}
+	if len(trie.root.children) != 0 {
+		t.Error("Expected empty nodes to be pruned")
+	}
+}
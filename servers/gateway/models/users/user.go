@@ -7,15 +7,26 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const minPasswordLength = 8
+const maxPasswordLength = 72 // bcrypt ignores anything longer
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,30}$`)
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// NormalizeEmail returns email as it is stored and looked up, so that
+// users are found whatever case they type their address in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func generatePhotoURL(email string) string {
-	cleaned := strings.TrimSpace(email)
-	cleaned = strings.ToLower(cleaned)
 	h := sha256.New()
-	h.Write([]byte(cleaned))
+	h.Write([]byte(NormalizeEmail(email)))
 	hash := hex.EncodeToString(h.Sum(nil))
 	return fmt.Sprintf("https://gravatar.com/avatar/%s", hash)
 }
@@ -29,22 +40,47 @@ func hashPassword(password string) (string, error) {
 }
 
 type NewUser struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
-	Email     string `json:"email"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordConf string `json:"passwordConf"`
+	Email        string `json:"email"`
 }
 
-func (nu *NewUser) Validate() error {
-	if nu.Password == "" {
+func validatePassword(password string) error {
+	if password == "" {
 		return errors.New("password cannot be blank")
 	}
-	matches, _ := regexp.MatchString(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`, nu.Email)
-	if !matches {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password cannot be longer than %d bytes", maxPasswordLength)
+	}
+	hasLetter := strings.IndexFunc(password, unicode.IsLetter) >= 0
+	hasDigit := strings.IndexFunc(password, unicode.IsDigit) >= 0
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain at least one letter and one digit")
+	}
+	return nil
+}
+
+func (nu *NewUser) Validate() error {
+	err := validatePassword(nu.Password)
+	if err != nil {
+		return err
+	}
+	if nu.PasswordConf != nu.Password {
+		return errors.New("password and password confirmation do not match")
+	}
+	if !usernamePattern.MatchString(nu.Username) {
+		return fmt.Errorf("invalid username: %s: must be 3 to 30 letters, digits, dots, dashes or underscores", nu.Username)
+	}
+	if !emailPattern.MatchString(nu.Email) {
 		return fmt.Errorf("invalid email address: %s", nu.Email)
 	}
-	matches, _ = regexp.MatchString(`^[^0-9]*$`, nu.FirstName)
+	matches, _ := regexp.MatchString(`^[^0-9]*$`, nu.FirstName)
 	if !matches {
 		return fmt.Errorf("invalid first name: %s", nu.FirstName)
 	}
@@ -60,7 +96,7 @@ func (nu *NewUser) ToUser() (*User, error) {
 		FirstName: nu.FirstName,
 		LastName:  nu.LastName,
 		Username:  nu.Username,
-		Email:     nu.Email,
+		Email:     NormalizeEmail(nu.Email),
 	}
 
 	u.PhotoURL = generatePhotoURL(nu.Email)
@@ -110,6 +146,22 @@ type Updates struct {
 	Email     string
 }
 
+// Validate checks the fields that are being changed. An email change is
+// not applied by ApplyUpdates; it has to be verified through an
+// EmailChange first.
+func (u *Updates) Validate() error {
+	if u.Password != "" {
+		err := validatePassword(u.Password)
+		if err != nil {
+			return err
+		}
+	}
+	if u.Email != "" && !emailPattern.MatchString(u.Email) {
+		return fmt.Errorf("invalid email address: %s", u.Email)
+	}
+	return nil
+}
+
 func (u *User) ApplyUpdates(updates *Updates) error {
 	if updates.FirstName != "" {
 		u.FirstName = updates.FirstName
@@ -118,14 +170,41 @@ func (u *User) ApplyUpdates(updates *Updates) error {
 		u.LastName = updates.LastName
 	}
 	if updates.Password != "" {
-		PassHash, err := hashPassword(updates.Password)
+		err := u.SetPassword(updates.Password)
 		if err != nil {
 			return err
 		}
-		u.PassHash = string(PassHash)
 	}
-	if updates.Email != "" {
-		u.PhotoURL = generatePhotoURL(updates.Email)
+	return nil
+}
+
+func (u *User) SetPassword(password string) error {
+	PassHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PassHash = PassHash
+	return nil
+}
+
+// PasswordReset sets a new password for a user who has forgotten theirs,
+// using a code emailed to them.
+type PasswordReset struct {
+	Code         string `json:"code"`
+	Password     string `json:"password"`
+	PasswordConf string `json:"passwordConf"`
+}
+
+func (pr *PasswordReset) Validate() error {
+	if pr.Code == "" {
+		return errors.New("reset code cannot be blank")
+	}
+	err := validatePassword(pr.Password)
+	if err != nil {
+		return err
+	}
+	if pr.PasswordConf != pr.Password {
+		return errors.New("password and password confirmation do not match")
 	}
 	return nil
 }
@@ -1,21 +1,35 @@
 package users
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestValidate(t *testing.T) {
 	cases := []struct {
 		input  *NewUser
 		output string
 	}{
-		{&NewUser{FirstName: "Bob", LastName: "Jones", Password: "password1", Email: "Bob.jones@gmail.com"}, "valid"},
-		{&NewUser{Password: "password1", Email: "Bob.jones@gmail.com"}, "valid"},
-		{&NewUser{Email: "Bob.jones@gmail.com"}, "invalid"},
-		{&NewUser{Password: "password1", Email: "bobbyjones"}, "invalid"},
-		{&NewUser{Password: "password1", Email: "bobbyjones@boby"}, "invalid"},
-		{&NewUser{Password: "password1", Email: "bobby.com"}, "invalid"},
-		{&NewUser{Password: "password1"}, "invalid"},
-		{&NewUser{FirstName: "bob1", Password: "password1", Email: "bobyjones@bob.com"}, "invalid"},
-		{&NewUser{LastName: "jones1", Password: "password1", Email: "bobyjones@bob.com"}, "invalid"},
+		{&NewUser{FirstName: "Bob", LastName: "Jones", Username: "bob.jones", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "valid"},
+		{&NewUser{Username: "bob_jones-2", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "valid"},
+		{&NewUser{Username: "bobjones", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", PasswordConf: "password1", Email: "bobbyjones"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", PasswordConf: "password1", Email: "bobbyjones@boby"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", PasswordConf: "password1", Email: "bobby.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", PasswordConf: "password1"}, "invalid"},
+		{&NewUser{FirstName: "bob1", Username: "bobjones", Password: "password1", PasswordConf: "password1", Email: "bobyjones@bob.com"}, "invalid"},
+		{&NewUser{LastName: "jones1", Username: "bobjones", Password: "password1", PasswordConf: "password1", Email: "bobyjones@bob.com"}, "invalid"},
+		{&NewUser{Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bo", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bob jones", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bob!", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "abcdefghijklmnopqrstuvwxyz12345", Password: "password1", PasswordConf: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", PasswordConf: "password2", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "pass1", PasswordConf: "pass1", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "password", PasswordConf: "password", Email: "Bob.jones@gmail.com"}, "invalid"},
+		{&NewUser{Username: "bobjones", Password: "12345678", PasswordConf: "12345678", Email: "Bob.jones@gmail.com"}, "invalid"},
 	}
 
 	for _, c := range cases {
@@ -37,7 +51,7 @@ func TestToUser(t *testing.T) {
 		},
 		{
 			&NewUser{Password: "password1", Email: " MyemailAddress@example.com  "},
-			&User{PhotoURL: "https://gravatar.com/avatar/84059b07d4be67b806386c0aad8070a23f18836bbaae342275dc0a83414c32ee", Email: " MyemailAddress@example.com  "},
+			&User{PhotoURL: "https://gravatar.com/avatar/84059b07d4be67b806386c0aad8070a23f18836bbaae342275dc0a83414c32ee", Email: "myemailaddress@example.com"},
 		},
 	}
 
@@ -132,6 +146,7 @@ func TestApplyUpdates(t *testing.T) {
 
 	for _, c := range cases {
 		oldPhoto := c.user.PhotoURL
+		oldEmail := c.user.Email
 		err := c.user.ApplyUpdates(c.updates)
 		if err != nil {
 			t.Errorf("Error applying updates: %s", err)
@@ -142,11 +157,56 @@ func TestApplyUpdates(t *testing.T) {
 		if c.updates.LastName != "" && c.user.LastName != c.updates.LastName {
 			t.Errorf("Expected LastName to be %s but got %s", c.updates.LastName, c.user.LastName)
 		}
-		if c.updates.Email != "" && c.user.PhotoURL == oldPhoto {
-			t.Errorf("Expected photo URL to change because of email address change")
+		if c.user.Email != oldEmail || c.user.PhotoURL != oldPhoto {
+			t.Errorf("Expected email changes to wait for verification")
 		}
 		if c.updates.Password != "" && !c.user.Authenticate(c.updates.Password) {
 			t.Errorf("New password %s failed to authenticate", c.updates.Password)
 		}
 	}
 }
+
+func TestEmailChangeToken(t *testing.T) {
+	user := &User{ID: 1, Email: "old@example.com"}
+	token, err := NewEmailChange(user, "new@example.com").Token("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseEmailChangeToken(token, []string{"other"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for the wrong secret, got: %v", err)
+	}
+	_, err = ParseEmailChangeToken(token+"x", []string{"secret"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for a tampered token, got: %v", err)
+	}
+
+	change, err := ParseEmailChangeToken(token, []string{"secret"})
+	if err != nil {
+		t.Fatalf("error parsing token: %s", err)
+	}
+	err = change.Apply(user)
+	if err != nil {
+		t.Fatalf("error applying change: %s", err)
+	}
+	if user.Email != "new@example.com" || user.PhotoURL != generatePhotoURL("new@example.com") {
+		t.Errorf("expected email and photo URL to change, got %s and %s", user.Email, user.PhotoURL)
+	}
+
+	err = change.Apply(user)
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken when applying a change twice, got: %v", err)
+	}
+
+	expired := NewEmailChange(user, "other@example.com")
+	expired.Expires = time.Now().Add(-time.Minute)
+	token, err = expired.Token("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseEmailChangeToken(token, []string{"secret"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an expired token, got: %v", err)
+	}
+}
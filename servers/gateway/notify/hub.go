@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"encoding/json"
+	"log"
+	"messaging-application/servers/gateway/events"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	sendBuffer = 64
+)
+
+type client struct {
+	userID int
+	conn   *websocket.Conn
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Hub tracks the open WebSocket connections of each user and pushes
+// events to them. It is safe for concurrent use.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[int]map[*client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: map[int]map[*client]struct{}{}}
+}
+
+// Register starts tracking conn for the given user. The hub owns the
+// connection from then on and closes it when the peer goes away or stops
+// answering pings.
+func (h *Hub) Register(userID int, conn *websocket.Conn) {
+	c := &client{
+		userID: userID,
+		conn:   conn,
+		send:   make(chan []byte, sendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[*client]struct{}{}
+	}
+	h.clients[userID][c] = struct{}{}
+	h.mu.Unlock()
+
+	go h.writePump(c)
+	go h.readPump(c)
+}
+
+// Connections returns the number of open connections for the given user.
+func (h *Hub) Connections(userID int) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID])
+}
+
+// TotalConnections returns the number of open connections of all users.
+func (h *Hub) TotalConnections() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	total := 0
+	for _, clients := range h.clients {
+		total += len(clients)
+	}
+	return total
+}
+
+// Notify sends the event to every open connection of the users allowed
+// to see it. Connections that cannot keep up are dropped.
+func (h *Hub) Notify(event *events.Event) error {
+	message, err := json.Marshal(struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}{event.Type, event.Payload})
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	targets := []*client{}
+	if len(event.UserIDs) == 0 {
+		for _, conns := range h.clients {
+			for c := range conns {
+				targets = append(targets, c)
+			}
+		}
+	} else {
+		for _, userID := range event.UserIDs {
+			for c := range h.clients[userID] {
+				targets = append(targets, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range targets {
+		select {
+		case c.send <- message:
+		case <-c.done:
+		default:
+			log.Printf("dropping slow websocket connection for user %d", c.userID)
+			h.unregister(c)
+		}
+	}
+	return nil
+}
+
+// Run notifies connected users of every event received until the
+// subscription is closed.
+func (h *Hub) Run(subscription <-chan *events.Event) {
+	for event := range subscription {
+		err := h.Notify(event)
+		if err != nil {
+			log.Printf("error notifying %s event: %v", event.Type, err)
+		}
+	}
+}
+
+func (h *Hub) unregister(c *client) {
+	c.once.Do(func() {
+		h.mu.Lock()
+		delete(h.clients[c.userID], c)
+		if len(h.clients[c.userID]) == 0 {
+			delete(h.clients, c.userID)
+		}
+		h.mu.Unlock()
+
+		close(c.done)
+	})
+}
+
+// readPump discards anything the client sends and keeps the read deadline
+// moving forward each time a pong arrives.
+func (h *Hub) readPump(c *client) {
+	defer h.unregister(c)
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writePump is the only goroutine that writes to the connection.
+func (h *Hub) writePump(c *client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.conn.WriteMessage(websocket.TextMessage, message)
+			if err != nil {
+				h.unregister(c)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				h.unregister(c)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"messaging-application/servers/gateway/events"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer registers every connection with hub under the user ID
+// given in the id query string parameter.
+func newTestServer(hub *Hub) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Register(userID, conn)
+	}))
+}
+
+func dial(t *testing.T, server *httptest.Server, userID int) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?id=" + strconv.Itoa(userID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for hub")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readEvent(conn *websocket.Conn) (*events.Event, error) {
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	event := &events.Event{}
+	err = json.Unmarshal(message, event)
+	return event, err
+}
+
+func TestNotifyTargetsUsers(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(hub)
+	defer server.Close()
+
+	first := dial(t, server, 1)
+	defer first.Close()
+	second := dial(t, server, 1)
+	defer second.Close()
+	other := dial(t, server, 2)
+	defer other.Close()
+	waitFor(t, func() bool { return hub.Connections(1) == 2 && hub.Connections(2) == 1 && hub.TotalConnections() == 3 })
+
+	err := hub.Notify(&events.Event{Type: "message-new", Payload: json.RawMessage(`{"id":1}`), UserIDs: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, conn := range []*websocket.Conn{first, second} {
+		event, err := readEvent(conn)
+		if err != nil {
+			t.Fatalf("Expected event to be delivered: %s", err)
+		}
+		if event.Type != "message-new" || string(event.Payload) != `{"id":1}` {
+			t.Errorf("Unexpected event %v", event)
+		}
+		if len(event.UserIDs) != 0 {
+			t.Error("Expected recipient list not to be sent to clients")
+		}
+	}
+
+	if _, err := readEvent(other); err == nil {
+		t.Error("Expected event not to be delivered to user 2")
+	}
+}
+
+func TestNotifyBroadcast(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(hub)
+	defer server.Close()
+
+	first := dial(t, server, 1)
+	defer first.Close()
+	second := dial(t, server, 2)
+	defer second.Close()
+	waitFor(t, func() bool { return hub.Connections(1) == 1 && hub.Connections(2) == 1 })
+
+	err := hub.Notify(&events.Event{Type: "channel-new", Payload: json.RawMessage(`{"id":3}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, conn := range []*websocket.Conn{first, second} {
+		if _, err := readEvent(conn); err != nil {
+			t.Errorf("Expected event to be delivered to everyone: %s", err)
+		}
+	}
+}
+
+func TestDisconnectUnregisters(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(hub)
+	defer server.Close()
+
+	conns := []*websocket.Conn{}
+	for i := 0; i < 10; i++ {
+		conns = append(conns, dial(t, server, 1))
+	}
+	waitFor(t, func() bool { return hub.Connections(1) == 10 })
+
+	for _, conn := range conns {
+		go conn.Close()
+	}
+	waitFor(t, func() bool { return hub.Connections(1) == 0 })
+
+	// Notifying a user with no connections is a no-op
+	err := hub.Notify(&events.Event{Type: "message-new", UserIDs: []int{1}})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunDeliversBusEvents(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server, 1)
+	defer conn.Close()
+	waitFor(t, func() bool { return hub.Connections(1) == 1 })
+
+	bus := events.NewMemoryBus()
+	subscription, err := bus.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		hub.Run(subscription)
+		close(done)
+	}()
+
+	err = bus.Publish(context.Background(), &events.Event{Type: "message-new", Payload: json.RawMessage(`{}`), UserIDs: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readEvent(conn); err != nil {
+		t.Errorf("Expected published event to be delivered: %s", err)
+	}
+
+	bus.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Run to return once the bus is closed")
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate allows Limit requests per Period. Each key has a token bucket that
+// holds up to Limit tokens and refills at Limit tokens per Period, so
+// bursts of up to Limit requests are allowed.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRate parses a rate written as limit/period, such as 10/1m.
+func ParseRate(s string) (Rate, error) {
+	limitStr, periodStr, found := strings.Cut(s, "/")
+	if !found {
+		return Rate{}, fmt.Errorf("invalid rate %q: must be limit/period", s)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 1 {
+		return Rate{}, fmt.Errorf("invalid rate %q: limit must be a positive integer", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: period must be a positive duration", s)
+	}
+	return Rate{Limit: limit, Period: period}, nil
+}
+
+// Result is the outcome of taking a token from a key's bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when the request was
+	// not allowed.
+	RetryAfter time.Duration
+}
+
+// DefaultTimeout is how long a RedisLimiter waits for each call by default.
+const DefaultTimeout = 3 * time.Second
+
+// Limiter decides whether another request identified by key may proceed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// newResult builds the result for a bucket left with tokens tokens.
+func newResult(rate Rate, allowed bool, tokens float64) Result {
+	perToken := rate.Period / time.Duration(rate.Limit)
+	res := Result{
+		Allowed:   allowed,
+		Limit:     rate.Limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(rate.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return res
+}
+
+// ParseRates parses a comma-separated list of name=rate pairs, such as
+// /v1/sessions=10/1m,/=300/1m.
+func ParseRates(config string) (map[string]Rate, error) {
+	rates := map[string]Rate{}
+	for _, pair := range strings.Split(config, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, rateStr, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid rate limit %q: must be name=limit/period", pair)
+		}
+		rate, err := ParseRate(rateStr)
+		if err != nil {
+			return nil, err
+		}
+		rates[name] = rate
+	}
+	return rates, nil
+}
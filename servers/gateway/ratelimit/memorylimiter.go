@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryLimiter keeps token buckets in process memory. It is local to one
+// gateway and meant for tests and single-instance setups.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	rate      Rate
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{
+		rate:      rate,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// refill returns the tokens b holds at now.
+func (l *MemoryLimiter) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last)
+	tokens := b.tokens + float64(l.rate.Limit)*elapsed.Seconds()/l.rate.Period.Seconds()
+	return min(tokens, float64(l.rate.Limit))
+}
+
+// sweep drops buckets that have refilled completely, since they are the
+// same as having no bucket. The caller must hold the lock.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Period {
+		return
+	}
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.rate.Limit) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Limit), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(l.rate, allowed, b.tokens), nil
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter(Rate{Limit: 2, Period: time.Minute})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(context.Background(), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed {
+			t.Errorf("expected request %d to be allowed", i+1)
+		}
+		if res.Remaining != 1-i {
+			t.Errorf("expected %d remaining but got %d", 1-i, res.Remaining)
+		}
+	}
+
+	res, _ := limiter.Allow(context.Background(), "a")
+	if res.Allowed {
+		t.Error("expected third request to be limited")
+	}
+	if res.RetryAfter != 30*time.Second {
+		t.Errorf("expected to retry after 30s but got %s", res.RetryAfter)
+	}
+	if res.Reset != time.Minute {
+		t.Errorf("expected the bucket to be full after 1m but got %s", res.Reset)
+	}
+
+	res, _ = limiter.Allow(context.Background(), "b")
+	if !res.Allowed {
+		t.Error("expected another key to have its own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	res, _ = limiter.Allow(context.Background(), "a")
+	if !res.Allowed {
+		t.Error("expected a token to be refilled after 30s")
+	}
+	res, _ = limiter.Allow(context.Background(), "a")
+	if res.Allowed {
+		t.Error("expected only one token to be refilled after 30s")
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter(Rate{Limit: 2, Period: time.Minute})
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow(context.Background(), "a")
+	now = now.Add(2 * time.Minute)
+	limiter.Allow(context.Background(), "b")
+
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Error("expected the full bucket to be swept")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected Rate
+		valid    bool
+	}{
+		{"10/1m", Rate{Limit: 10, Period: time.Minute}, true},
+		{" 5 / 15m ", Rate{Limit: 5, Period: 15 * time.Minute}, true},
+		{"10", Rate{}, false},
+		{"0/1m", Rate{}, false},
+		{"10/soon", Rate{}, false},
+		{"10/-1s", Rate{}, false},
+	}
+
+	for _, c := range cases {
+		rate, err := ParseRate(c.input)
+		if c.valid && err != nil {
+			t.Errorf("unexpected error parsing %q: %s", c.input, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected an error parsing %q", c.input)
+		}
+		if rate != c.expected {
+			t.Errorf("expected %+v parsing %q but got %+v", c.expected, c.input, rate)
+		}
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates("/v1/sessions=10/1m, /=300/1m,")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rates) != 2 {
+		t.Errorf("expected 2 rates but got %d", len(rates))
+	}
+	if rates["/v1/sessions"] != (Rate{Limit: 10, Period: time.Minute}) {
+		t.Errorf("unexpected rate for /v1/sessions: %+v", rates["/v1/sessions"])
+	}
+	if rates["/"] != (Rate{Limit: 300, Period: time.Minute}) {
+		t.Errorf("unexpected rate for /: %+v", rates["/"])
+	}
+
+	for _, config := range []string{"/v1/sessions", "=10/1m", "/=lots"} {
+		_, err = ParseRates(config)
+		if err == nil {
+			t.Errorf("expected an error parsing %q", config)
+		}
+	}
+}
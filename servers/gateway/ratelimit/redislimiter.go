@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"messaging-application/servers/gateway/timeout"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket takes a token from the bucket at KEYS[1] atomically. ARGV[1]
+// is the bucket size and ARGV[2] the milliseconds it takes to refill it.
+// The server clock is used so that replicas with skewed clocks agree.
+var tokenBucket = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil then
+  tokens = limit
+  last = now
+end
+
+tokens = math.min(limit, tokens + (now - last) * limit / period)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter keeps token buckets in Redis so that limits hold across
+// every gateway replica.
+type RedisLimiter struct {
+	rdb    *redis.Client
+	prefix string
+	rate   Rate
+
+	// Timeout bounds each call to Redis. Zero leaves calls bounded only
+	// by their context.
+	Timeout time.Duration
+}
+
+func NewRedisLimiter(client *redis.Client, prefix string, rate Rate) *RedisLimiter {
+	return &RedisLimiter{
+		rdb:     client,
+		prefix:  prefix,
+		rate:    rate,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	ctx, cancel := timeout.Context(ctx, l.Timeout)
+	defer cancel()
+
+	keys := []string{l.prefix + ":" + key}
+	reply, err := tokenBucket.Run(ctx, l.rdb, keys, l.rate.Limit, l.rate.Period.Milliseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensStr, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(l.rate, allowed == 1, tokens), nil
+}
@@ -0,0 +1,43 @@
+//go:build !no_db
+
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	addr := os.Getenv("REDISADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	limiter := NewRedisLimiter(client, "ratelimit:test", Rate{Limit: 2, Period: time.Minute})
+	client.Del(context.Background(), "ratelimit:test:a")
+
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(context.Background(), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed {
+			t.Errorf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	res, err := limiter.Allow(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed {
+		t.Error("expected third request to be limited")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > 30*time.Second {
+		t.Errorf("expected to retry within 30s but got %s", res.RetryAfter)
+	}
+}
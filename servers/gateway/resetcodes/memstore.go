@@ -0,0 +1,93 @@
+package resetcodes
+
+import (
+	"context"
+	"crypto/hmac"
+	"slices"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	hash     string
+	failures int
+	expires  time.Time
+}
+
+// MemoryStore keeps reset codes in process memory, for tests and single
+// instance setups.
+type MemoryStore struct {
+	mu      sync.Mutex
+	exp     time.Duration
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+func NewMemoryStore(expiration time.Duration) *MemoryStore {
+	return &MemoryStore{
+		exp:     expiration,
+		entries: map[string]*entry{},
+		now:     time.Now,
+	}
+}
+
+// get returns the live entry for email. The caller must hold the lock.
+func (m *MemoryStore) get(email string) (*entry, error) {
+	e, ok := m.entries[email]
+	if !ok || !m.now().Before(e.expires) {
+		delete(m.entries, email)
+		return nil, ErrCodeNotFound
+	}
+	return e, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, email string, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[email] = &entry{hash: hash, expires: m.now().Add(m.exp)}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, email string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.get(email)
+	if err != nil {
+		return "", err
+	}
+	return e.hash, nil
+}
+
+func (m *MemoryStore) Consume(ctx context.Context, email string, hashes []string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.get(email)
+	if err != nil {
+		return false, err
+	}
+	if !slices.ContainsFunc(hashes, func(hash string) bool {
+		return hmac.Equal([]byte(e.hash), []byte(hash))
+	}) {
+		return false, nil
+	}
+	delete(m.entries, email)
+	return true, nil
+}
+
+func (m *MemoryStore) Fail(ctx context.Context, email string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.get(email)
+	if err != nil {
+		return 0, err
+	}
+	e.failures++
+	return e.failures, nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, email)
+	return nil
+}
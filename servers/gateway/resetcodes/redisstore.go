@@ -0,0 +1,115 @@
+package resetcodes
+
+import (
+	"context"
+	"errors"
+	"messaging-application/servers/gateway/timeout"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps reset codes in Redis so that any gateway replica can
+// redeem a code another one issued.
+type RedisStore struct {
+	rdb *redis.Client
+	exp time.Duration
+
+	// Timeout bounds each call to Redis. Zero leaves calls bounded only
+	// by their context.
+	Timeout time.Duration
+}
+
+func NewRedisStore(client *redis.Client, expiration time.Duration) *RedisStore {
+	return &RedisStore{
+		rdb:     client,
+		exp:     expiration,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func codeKey(email string) string {
+	return "resetcode:" + email
+}
+
+func failuresKey(email string) string {
+	return "resetcode:" + email + ":failures"
+}
+
+func (rs *RedisStore) Set(ctx context.Context, email string, hash string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	pipe := rs.rdb.TxPipeline()
+	pipe.Set(ctx, codeKey(email), hash, rs.exp)
+	pipe.Del(ctx, failuresKey(email))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisStore) Get(ctx context.Context, email string) (string, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	hash, err := rs.rdb.Get(ctx, codeKey(email)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCodeNotFound
+	}
+	return hash, err
+}
+
+// consumeScript deletes a code and its failures if the code's hash is one
+// of ARGV. It returns -1 if there is no code, 1 if it was deleted and 0 if
+// the hash did not match.
+var consumeScript = redis.NewScript(`
+local hash = redis.call("GET", KEYS[1])
+if not hash then
+	return -1
+end
+for _, candidate in ipairs(ARGV) do
+	if candidate == hash then
+		redis.call("DEL", KEYS[1], KEYS[2])
+		return 1
+	end
+end
+return 0
+`)
+
+func (rs *RedisStore) Consume(ctx context.Context, email string, hashes []string) (bool, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	args := make([]any, len(hashes))
+	for i, hash := range hashes {
+		args[i] = hash
+	}
+	result, err := consumeScript.Run(ctx, rs.rdb, []string{codeKey(email), failuresKey(email)}, args...).Int()
+	if err != nil {
+		return false, err
+	}
+	if result < 0 {
+		return false, ErrCodeNotFound
+	}
+	return result == 1, nil
+}
+
+func (rs *RedisStore) Fail(ctx context.Context, email string) (int, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	pipe := rs.rdb.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey(email))
+	pipe.Expire(ctx, failuresKey(email), rs.exp)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(failures.Val()), nil
+}
+
+func (rs *RedisStore) Delete(ctx context.Context, email string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+
+	return rs.rdb.Del(ctx, codeKey(email), failuresKey(email)).Err()
+}
@@ -0,0 +1,41 @@
+//go:build !no_db
+
+package resetcodes
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("REDISADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: addr}), time.Minute)
+
+	code, err := Issue(context.Background(), "jon@example.com", secret, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", "wrong", []string{secret}, store)
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode for a wrong code, got: %v", err)
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+	if err != nil {
+		t.Errorf("error redeeming code: %s", err)
+	}
+
+	_, err = store.Get(context.Background(), "jon@example.com")
+	if !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("expected ErrCodeNotFound after redeeming, got: %v", err)
+	}
+}
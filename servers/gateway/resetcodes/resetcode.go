@@ -0,0 +1,93 @@
+package resetcodes
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"messaging-application/servers/gateway/models/users"
+)
+
+const CODE_LENGTH = 6
+
+// maxAttempts is how many wrong codes are accepted before the code is
+// discarded and a new one has to be requested.
+const maxAttempts = 5
+
+var ErrInvalidCode = errors.New("invalid or expired reset code")
+
+// KeyPurpose is the purpose reset codes derive their secrets for from the
+// gateway's keyring.
+const KeyPurpose = "resetcodes"
+
+// hashCode keys the hash with secret so that leaked hashes of the short
+// codes cannot be reversed by trying every code.
+func hashCode(code string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newCode() (string, error) {
+	code := make([]byte, CODE_LENGTH)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// Issue returns a new one-time code for email. Only its hash is stored.
+func Issue(ctx context.Context, email string, secret string, store Store) (string, error) {
+	code, err := newCode()
+	if err != nil {
+		return "", err
+	}
+	err = store.Set(ctx, users.NormalizeEmail(email), hashCode(code, secret))
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Redeem consumes the code issued to email, checking its hash against
+// each of secrets in turn. It returns ErrInvalidCode if the code is wrong,
+// has expired or has already been used. Of concurrent calls with the same
+// code, only one succeeds.
+func Redeem(ctx context.Context, email string, code string, secrets []string, store Store) error {
+	email = users.NormalizeEmail(email)
+	hashes := make([]string, len(secrets))
+	for i, secret := range secrets {
+		hashes[i] = hashCode(code, secret)
+	}
+
+	consumed, err := store.Consume(ctx, email, hashes)
+	if errors.Is(err, ErrCodeNotFound) {
+		return ErrInvalidCode
+	} else if err != nil {
+		return err
+	}
+	if consumed {
+		return nil
+	}
+
+	failures, err := store.Fail(ctx, email)
+	if errors.Is(err, ErrCodeNotFound) {
+		return ErrInvalidCode
+	} else if err != nil {
+		return err
+	}
+	if failures >= maxAttempts {
+		err = store.Delete(ctx, email)
+		if err != nil {
+			return err
+		}
+	}
+	return ErrInvalidCode
+}
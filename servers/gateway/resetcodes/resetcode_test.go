@@ -0,0 +1,122 @@
+package resetcodes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const secret = "c2VjcmV0"
+
+func TestIssueAndRedeem(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	code, err := Issue(context.Background(), "Jon@Example.com", secret, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != CODE_LENGTH {
+		t.Errorf("expected a %d digit code but got %s", CODE_LENGTH, code)
+	}
+
+	hash, err := store.Get(context.Background(), "jon@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == code {
+		t.Error("expected the code to be stored hashed")
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+	if err != nil {
+		t.Errorf("error redeeming code: %s", err)
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode when redeeming a code twice, got: %v", err)
+	}
+}
+
+func TestRedeemWrongCode(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	code, err := Issue(context.Background(), "jon@example.com", secret, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "x" + code[1:]
+
+	for i := 0; i < maxAttempts; i++ {
+		err = Redeem(context.Background(), "jon@example.com", wrong, []string{secret}, store)
+		if !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("expected ErrInvalidCode for a wrong code, got: %v", err)
+		}
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected the code to be discarded after %d wrong attempts, got: %v", maxAttempts, err)
+	}
+}
+
+func TestRedeemExpiredCode(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(time.Minute)
+	store.now = func() time.Time { return now }
+
+	code, err := Issue(context.Background(), "jon@example.com", secret, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode for an expired code, got: %v", err)
+	}
+}
+
+func TestRedeemWithPreviousSecret(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	code, err := Issue(context.Background(), "jon@example.com", "b2xk", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Redeem(context.Background(), "jon@example.com", code, []string{secret, "b2xk"}, store)
+	if err != nil {
+		t.Errorf("expected a code hashed with a previous secret to be redeemed, got: %v", err)
+	}
+}
+
+func TestRedeemConcurrently(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	code, err := Issue(context.Background(), "jon@example.com", secret, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			errs <- Redeem(context.Background(), "jon@example.com", code, []string{secret}, store)
+		}()
+	}
+
+	redeemed := 0
+	for range 2 {
+		err := <-errs
+		if err == nil {
+			redeemed++
+		} else if !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("expected ErrInvalidCode for the losing redemption, got: %v", err)
+		}
+	}
+	if redeemed != 1 {
+		t.Errorf("expected the code to be redeemed once but it was redeemed %d times", redeemed)
+	}
+}
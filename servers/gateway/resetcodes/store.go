@@ -0,0 +1,32 @@
+package resetcodes
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrCodeNotFound = errors.New("reset code not found")
+
+// DefaultTimeout is how long a RedisStore waits for each call by default.
+const DefaultTimeout = 3 * time.Second
+
+// Store holds the hashes of the reset codes issued to each email address.
+// Codes expire after a fixed time.
+type Store interface {
+	// Set stores the hash of a new code for email, replacing any earlier
+	// code and its failed attempts.
+	Set(ctx context.Context, email string, hash string) error
+	// Get returns the hash of the code issued to email, or
+	// ErrCodeNotFound if there is none or it has expired.
+	Get(ctx context.Context, email string) (string, error)
+	// Consume deletes the code issued to email if its hash is one of
+	// hashes, and reports whether it did. The check and the delete are
+	// atomic, so a code is only ever consumed once. It returns
+	// ErrCodeNotFound if there is no code or it has expired.
+	Consume(ctx context.Context, email string, hashes []string) (bool, error)
+	// Fail records a wrong code for email and returns the number of wrong
+	// codes since the current one was issued.
+	Fail(ctx context.Context, email string) (int, error)
+	Delete(ctx context.Context, email string) error
+}
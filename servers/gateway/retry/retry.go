@@ -0,0 +1,40 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff waits Base before the second attempt and twice as long before
+// each attempt after that, up to Max, giving up after Attempts tries.
+type Backoff struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+// Do calls fn until it succeeds, the attempts run out or ctx is done. It
+// returns the last error from fn, or ctx's error. onRetry, if not nil, is
+// called with each failure and the wait before the next attempt.
+func (b Backoff) Do(ctx context.Context, fn func() error, onRetry func(attempt int, err error, wait time.Duration)) error {
+	wait := b.Base
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= b.Attempts {
+			return err
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(wait*2, b.Max)
+	}
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoSucceeds(t *testing.T) {
+	calls := 0
+	var waits []time.Duration
+	b := Backoff{Attempts: 5, Base: time.Millisecond, Max: 3 * time.Millisecond}
+
+	err := b.Do(context.Background(), func() error {
+		calls++
+		if calls < 4 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, func(attempt int, err error, wait time.Duration) {
+		waits = append(waits, wait)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 calls but got %d", calls)
+	}
+
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	if len(waits) != len(expected) {
+		t.Fatalf("expected waits %v but got %v", expected, waits)
+	}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Errorf("expected waits %v but got %v", expected, waits)
+			break
+		}
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	calls := 0
+	failure := errors.New("down")
+	b := Backoff{Attempts: 3, Base: time.Millisecond, Max: time.Millisecond}
+
+	err := b.Do(context.Background(), func() error {
+		calls++
+		return failure
+	}, nil)
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the last error but got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls but got %d", calls)
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := Backoff{Attempts: 3, Base: time.Hour, Max: time.Hour}
+
+	err := b.Do(ctx, func() error { return errors.New("down") }, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package sessions
+
+import "time"
+
+// OpenMigratedDB returns a new SQLite database with every migration
+// applied.
+var OpenMigratedDB = openMigratedDB
+
+// SetClock makes store read the time from now, so that tests outside the
+// package can control expiration.
+func SetClock(store Store, now func() time.Time) {
+	switch s := store.(type) {
+	case *MemoryStore:
+		s.now = now
+	case *SQLiteStore:
+		s.now = now
+	}
+}
@@ -0,0 +1,162 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionPurpose is the purpose session tokens derive their keys for.
+const sessionPurpose = "sessions"
+
+// DefaultTokenLifetime is how long a session token is valid after it is
+// issued by default, however recently the session was used.
+const DefaultTokenLifetime = 30 * 24 * time.Hour
+
+// Key is a key that session tokens are signed with. Its ID is written into
+// every token it signs, so that the token can be verified with the same key
+// after the key has been rotated.
+type Key struct {
+	ID byte
+	// Secret is the key itself, in URL-safe base64.
+	Secret string
+	// RetireAt is when a previous key stops verifying tokens. It is zero
+	// for the current key.
+	RetireAt time.Time
+}
+
+// ParseKey parses a key written as ID:SECRET, or as SECRET alone for a key
+// with ID 0. Previous keys end with @ and the RFC 3339 time they retire,
+// as in 1:SECRET@2024-06-01T00:00:00Z.
+func ParseKey(s string) (Key, error) {
+	key := Key{}
+	s, retireAt, retired := strings.Cut(s, "@")
+	if retired {
+		t, err := time.Parse(time.RFC3339, retireAt)
+		if err != nil {
+			return Key{}, fmt.Errorf("error parsing key retirement time: %w", err)
+		}
+		key.RetireAt = t
+	}
+
+	idText, secret, found := strings.Cut(s, ":")
+	if !found {
+		key.Secret = s
+		return key, nil
+	}
+	id, err := strconv.ParseUint(idText, 10, 8)
+	if err != nil {
+		return Key{}, fmt.Errorf("key ID must be a number from 0 to 255: %w", err)
+	}
+	key.ID = byte(id)
+	key.Secret = secret
+	return key, nil
+}
+
+type keyringEntry struct {
+	secret   []byte
+	retireAt time.Time
+}
+
+// Keyring holds the keys that session tokens and other tokens are signed
+// with. Each purpose derives its own secret from a key, so no two kinds of
+// token share a secret. New tokens are always signed with the current key.
+// Previous keys only verify tokens, and only until they retire, so that
+// the key can be rotated without invalidating every token at once.
+type Keyring struct {
+	current Key
+	entries map[byte]keyringEntry
+	now     func() time.Time
+
+	// TokenLifetime is how long session tokens are valid after they are
+	// issued.
+	TokenLifetime time.Duration
+	// LegacyUntil is when session tokens from before versioning, which
+	// carry no expiration of their own, stop being accepted, whether key 0
+	// is the current key or a previous one. Zero rejects them.
+	LegacyUntil time.Time
+}
+
+// NewKeyring returns a keyring that signs with current and also verifies
+// with the previous keys until their retirement times.
+func NewKeyring(current Key, previous []Key) (*Keyring, error) {
+	if !current.RetireAt.IsZero() {
+		return nil, fmt.Errorf("the current key %d cannot have a retirement time", current.ID)
+	}
+	k := &Keyring{
+		current:       current,
+		entries:       map[byte]keyringEntry{},
+		now:           time.Now,
+		TokenLifetime: DefaultTokenLifetime,
+	}
+
+	for i, key := range append([]Key{current}, previous...) {
+		if i > 0 && key.RetireAt.IsZero() {
+			return nil, fmt.Errorf("previous key %d needs a retirement time", key.ID)
+		}
+		if _, ok := k.entries[key.ID]; ok {
+			return nil, fmt.Errorf("more than one key has ID %d", key.ID)
+		}
+		secret, err := base64.URLEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding key %d: %w", key.ID, err)
+		}
+		k.entries[key.ID] = keyringEntry{secret: secret, retireAt: key.RetireAt}
+	}
+	return k, nil
+}
+
+// Current returns the key new tokens are signed with.
+func (k *Keyring) Current() Key {
+	return k.current
+}
+
+// secret returns the secret of the key with id, if that key may still be
+// used to verify tokens.
+func (k *Keyring) secret(id byte) ([]byte, bool) {
+	entry, ok := k.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !entry.retireAt.IsZero() && !k.now().Before(entry.retireAt) {
+		return nil, false
+	}
+	return entry.secret, true
+}
+
+// acceptsLegacy reports whether tokens from before versioning are still
+// accepted.
+func (k *Keyring) acceptsLegacy() bool {
+	return k.now().Before(k.LegacyUntil)
+}
+
+// derive returns the secret for purpose derived from the secret of a key.
+func derive(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Secret returns the current key's secret for purpose, to sign new tokens
+// with.
+func (k *Keyring) Secret(purpose string) string {
+	return hex.EncodeToString(derive(k.entries[k.current.ID].secret, purpose))
+}
+
+// Secrets returns the secrets for purpose of every key that may still
+// verify tokens, starting with the current key.
+func (k *Keyring) Secrets(purpose string) []string {
+	secrets := []string{k.Secret(purpose)}
+	for id := range k.entries {
+		secret, ok := k.secret(id)
+		if ok && id != k.current.ID {
+			secrets = append(secrets, hex.EncodeToString(derive(secret, purpose)))
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,231 @@
+package sessions
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseKey(t *testing.T) {
+	retireAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		input    string
+		expected Key
+		valid    bool
+	}{
+		{"c2VjcmV0", Key{ID: 0, Secret: "c2VjcmV0"}, true},
+		{"7:c2VjcmV0", Key{ID: 7, Secret: "c2VjcmV0"}, true},
+		{"255:c2VjcmV0", Key{ID: 255, Secret: "c2VjcmV0"}, true},
+		{"7:c2VjcmV0@2024-06-01T00:00:00Z", Key{ID: 7, Secret: "c2VjcmV0", RetireAt: retireAt}, true},
+		{"c2VjcmV0@2024-06-01T00:00:00Z", Key{ID: 0, Secret: "c2VjcmV0", RetireAt: retireAt}, true},
+		{"7:c2VjcmV0@tomorrow", Key{}, false},
+		{"256:c2VjcmV0", Key{}, false},
+		{"one:c2VjcmV0", Key{}, false},
+	}
+
+	for _, c := range cases {
+		key, err := ParseKey(c.input)
+		if c.valid && (err != nil || key != c.expected) {
+			t.Errorf("expected %q to parse as %+v but got %+v, %v", c.input, c.expected, key, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected an error parsing %q", c.input)
+		}
+	}
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	retireAt := time.Now().Add(time.Hour)
+	_, err := NewKeyring(Key{ID: 1, Secret: "c2VjcmV0"}, []Key{{ID: 1, Secret: "b3RoZXI=", RetireAt: retireAt}})
+	if err == nil {
+		t.Error("expected an error for two keys with the same ID")
+	}
+	_, err = NewKeyring(Key{ID: 1, Secret: "not base64!"}, nil)
+	if err == nil {
+		t.Error("expected an error for a secret that is not base64")
+	}
+	_, err = NewKeyring(Key{ID: 1, Secret: "c2VjcmV0"}, []Key{{ID: 2, Secret: "b3RoZXI="}})
+	if err == nil {
+		t.Error("expected an error for a previous key without a retirement time")
+	}
+	_, err = NewKeyring(Key{ID: 1, Secret: "c2VjcmV0", RetireAt: retireAt}, nil)
+	if err == nil {
+		t.Error("expected an error for a current key with a retirement time")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	store := NewMemoryStore("1h")
+	oldKey := Key{ID: 1, Secret: "b2xk"}
+	newKey := Key{ID: 2, Secret: "bmV3"}
+
+	oldToken, err := BeginSession(ctx, 1, "state", newKeyring(oldKey), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	retired := oldKey
+	retired.RetireAt = now.Add(time.Hour)
+	rotated := newKeyring(newKey, retired)
+	rotated.now = func() time.Time { return now }
+
+	newToken, err := BeginSession(ctx, 1, "state", rotated, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenBytes, _ := base64.URLEncoding.DecodeString(newToken)
+	if tokenBytes[0] != tokenVersion || tokenBytes[1] != newKey.ID {
+		t.Errorf("expected a version %d token signed with key %d but got version %d and key %d",
+			tokenVersion, newKey.ID, tokenBytes[0], tokenBytes[1])
+	}
+
+	// The old key verifies existing sessions until it retires
+	_, err = GetSessionState(ctx, oldToken, rotated, store)
+	if err != nil {
+		t.Errorf("expected a token signed with the previous key to be valid, got: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	_, err = GetSessionState(ctx, oldToken, rotated, store)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for the previous key after it retired, got: %v", err)
+	}
+	_, err = GetSessionID(newToken, rotated)
+	if err != nil {
+		t.Errorf("expected a token signed with the current key to stay valid, got: %v", err)
+	}
+
+	// Tokens signed with keys that are not in the keyring are rejected
+	_, err = GetSessionID(newToken, newKeyring(oldKey))
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for an unknown key, got: %v", err)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	store := NewMemoryStore("1h")
+	keys := newKeyring(Key{ID: 1, Secret: "c2VjcmV0"})
+	keys.TokenLifetime = 24 * time.Hour
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	token, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(23 * time.Hour)
+	_, err = GetSessionID(token, keys)
+	if err != nil {
+		t.Errorf("expected the token to be valid before it expires, got: %v", err)
+	}
+
+	// The session state is still stored, but the token no longer reaches it
+	now = now.Add(time.Hour)
+	_, err = GetSessionState(ctx, token, keys, store)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for an expired token, got: %v", err)
+	}
+
+	// The expiration is signed, so it cannot be pushed back
+	tokenBytes, _ := base64.URLEncoding.DecodeString(token)
+	tokenBytes[2] = 0xff
+	_, err = GetSessionID(encode(tokenBytes), keys)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a token with a changed expiration, got: %v", err)
+	}
+}
+
+func TestUnversionedTokens(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore("1h")
+	id := make([]byte, SESSIONID_LENGTH)
+	secret, _ := base64.URLEncoding.DecodeString("c2VjcmV0")
+	signature, _ := signID(id, secret)
+	token := encode(append(id, signature...))
+	store.Set(ctx, encode(id), "state")
+
+	// Key 0 is the current key, but tokens from before versioning are
+	// still only accepted until the cutoff
+	current := newKeyring(Key{Secret: "c2VjcmV0"})
+	current.now = func() time.Time { return now }
+	_, err := GetSessionID(token, current)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a token from before versioning without a cutoff, got: %v", err)
+	}
+
+	current.LegacyUntil = now.Add(time.Hour)
+	state, err := GetSessionState(ctx, token, current, store)
+	if err != nil || state != "state" {
+		t.Errorf("expected a token from before versioning to be valid with key 0 before the cutoff, got %q, %v", state, err)
+	}
+
+	now = now.Add(time.Hour)
+	_, err = GetSessionID(token, current)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a token from before versioning after the cutoff, got: %v", err)
+	}
+
+	rotated := newKeyring(Key{ID: 1, Secret: "c2VjcmV0"})
+	rotated.LegacyUntil = now.Add(time.Hour)
+	_, err = GetSessionID(token, rotated)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a token from before versioning without key 0, got: %v", err)
+	}
+}
+
+func TestMalformedTokens(t *testing.T) {
+	store := NewMemoryStore("1h")
+	token, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenBytes, _ := base64.URLEncoding.DecodeString(token)
+
+	wrongVersion := append([]byte{}, tokenBytes...)
+	wrongVersion[0] = tokenVersion + 1
+
+	cases := map[string]string{
+		"empty":         "",
+		"short":         encode([]byte("short")),
+		"truncated":     encode(tokenBytes[:len(tokenBytes)-1]),
+		"extended":      encode(append(tokenBytes, 0)),
+		"wrong version": encode(wrongVersion),
+	}
+	for name, token := range cases {
+		_, err := GetSessionState(ctx, token, keys, store)
+		if !errors.Is(err, ErrInvalidID) {
+			t.Errorf("expected ErrInvalidID for a %s token, got: %v", name, err)
+		}
+		err = EndSession(ctx, 1, token, store)
+		if !errors.Is(err, ErrInvalidID) {
+			t.Errorf("expected ErrInvalidID ending a %s token, got: %v", name, err)
+		}
+	}
+}
+
+func TestKeyringSecrets(t *testing.T) {
+	oldKey := Key{ID: 1, Secret: "b2xk", RetireAt: time.Now().Add(time.Hour)}
+	keys := newKeyring(Key{ID: 2, Secret: "bmV3"}, oldKey)
+	old := newKeyring(Key{ID: 1, Secret: "b2xk"})
+
+	if keys.Secret("a") == keys.Secret("b") {
+		t.Error("expected each purpose to have its own secret")
+	}
+	if keys.Secret("a") == old.Secret("a") {
+		t.Error("expected each key to have its own secret")
+	}
+
+	secrets := keys.Secrets("a")
+	if len(secrets) != 2 || secrets[0] != keys.Secret("a") || secrets[1] != old.Secret("a") {
+		t.Errorf("expected the current then the previous key's secret but got %v", secrets)
+	}
+
+	keys.now = func() time.Time { return oldKey.RetireAt }
+	secrets = keys.Secrets("a")
+	if len(secrets) != 1 {
+		t.Errorf("expected a retired key's secret to be left out but got %v", secrets)
+	}
+}
@@ -1,33 +1,201 @@
 package sessions
 
-import "errors"
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
 
+type memEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// MemoryStore keeps session states in process memory, for tests and
+// single instance setups. Like RedisStore, reading a session extends its
+// expiration. Expired sessions are removed when they are next used, or by
+// Sweep. It is safe for concurrent use.
 type MemoryStore struct {
-	store map[string]string
+	mu sync.Mutex
+	// entries holds the element of each key in lru, which is ordered from
+	// the most to the least recently used
+	entries  map[string]*list.Element
+	lru      *list.List
+	sessions map[int]map[string]struct{}
+	exp      time.Duration
+	now      func() time.Time
+
+	// MaxEntries caps how many session states are kept. When a new state
+	// would exceed it, the least recently used state is evicted. Zero
+	// means no cap.
+	MaxEntries int
 }
 
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{store: map[string]string{}}
+func NewMemoryStore(expiration string) *MemoryStore {
+	m := &MemoryStore{
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		sessions: map[int]map[string]struct{}{},
+		now:      time.Now,
+	}
+	m.exp, _ = time.ParseDuration(expiration)
+	return m
 }
 
-func (m *MemoryStore) Get(key string) (string, error) {
-	value, ok := m.store[key]
+// get returns the element of the live entry for key, removing it if it
+// has expired. The caller must hold the lock.
+func (m *MemoryStore) get(key string) (*list.Element, error) {
+	elem, ok := m.entries[key]
 	if !ok {
-		return "", errors.New("no matches to given token in session store")
+		return nil, ErrStateNotFound
+	}
+	if !m.now().Before(elem.Value.(*memEntry).expires) {
+		m.remove(elem)
+		return nil, ErrStateNotFound
+	}
+	return elem, nil
+}
+
+// remove removes the entry of elem. The caller must hold the lock.
+func (m *MemoryStore) remove(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*memEntry).key)
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, err := m.get(key)
+	if err != nil {
+		return "", err
 	}
-	return value, nil
+	entry := elem.Value.(*memEntry)
+	entry.expires = m.now().Add(m.exp)
+	m.lru.MoveToFront(elem)
+	return entry.value, nil
 }
 
-func (m *MemoryStore) Set(key string, value string) error {
-	m.store[key] = value
+func (m *MemoryStore) Set(ctx context.Context, key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := &memEntry{key: key, value: value, expires: m.now().Add(m.exp)}
+	if elem, ok := m.entries[key]; ok {
+		elem.Value = entry
+		m.lru.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[key] = m.lru.PushFront(entry)
+	if m.MaxEntries > 0 && m.lru.Len() > m.MaxEntries {
+		m.remove(m.lru.Back())
+	}
 	return nil
 }
 
-func (m *MemoryStore) Delete(key string) error {
-	_, ok := m.store[key]
-	if !ok {
-		return errors.New("no matches to given token in session store")
+func (m *MemoryStore) Replace(ctx context.Context, key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, err := m.get(key)
+	if err != nil {
+		return err
+	}
+	elem.Value.(*memEntry).value = value
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, err := m.get(key)
+	if err != nil {
+		return err
 	}
-	delete(m.store, key)
+	m.remove(elem)
 	return nil
 }
+
+func (m *MemoryStore) AddSessionID(ctx context.Context, userID int, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sessions[userID] == nil {
+		m.sessions[userID] = map[string]struct{}{}
+	}
+	m.sessions[userID][sessionID] = struct{}{}
+	return nil
+}
+
+func (m *MemoryStore) RemoveSessionID(ctx context.Context, userID int, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions[userID], sessionID)
+	if len(m.sessions[userID]) == 0 {
+		delete(m.sessions, userID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetSessionIDs(ctx context.Context, userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []string{}
+	for id := range m.sessions[userID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Sweep removes expired sessions, and sessions that are no longer stored
+// from their users' indexes. It returns how many sessions were removed.
+func (m *MemoryStore) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	swept := 0
+	now := m.now()
+	for _, elem := range m.entries {
+		if !now.Before(elem.Value.(*memEntry).expires) {
+			m.remove(elem)
+			swept++
+		}
+	}
+
+	for userID, ids := range m.sessions {
+		for id := range ids {
+			if _, ok := m.entries[id]; !ok {
+				delete(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			delete(m.sessions, userID)
+		}
+	}
+	return swept
+}
+
+// StartSweeper calls Sweep every interval until the returned function is
+// called.
+func (m *MemoryStore) StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.Sweep()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
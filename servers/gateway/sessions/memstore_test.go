@@ -0,0 +1,129 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newMemoryStore returns a store whose clock is controlled by the
+// returned function.
+func newMemoryStore(expiration string) (*MemoryStore, func(time.Duration)) {
+	store := NewMemoryStore(expiration)
+	var mu sync.Mutex
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return store, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestMemoryStoreConcurrentUse(t *testing.T) {
+	store := NewMemoryStore("1h")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				key := fmt.Sprintf("%d-%d", i, j%10)
+				store.Set(ctx, key, "state")
+				store.Get(ctx, key)
+				store.AddSessionID(ctx, i, key)
+				store.GetSessionIDs(ctx, i)
+				if j%3 == 0 {
+					store.Delete(ctx, key)
+					store.RemoveSessionID(ctx, i, key)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			store.Sweep()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestMemoryStoreMaxEntries(t *testing.T) {
+	store := NewMemoryStore("1h")
+	store.MaxEntries = 2
+
+	store.Set(ctx, "a", "1")
+	store.Set(ctx, "b", "2")
+	// Reading a makes b the least recently used
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", "3")
+
+	_, err := store.Get(ctx, "b")
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected b to be evicted but got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		_, err := store.Get(ctx, key)
+		if err != nil {
+			t.Errorf("expected %s to be kept but got %v", key, err)
+		}
+	}
+
+	// Overwriting a key does not evict anything
+	store.Set(ctx, "a", "4")
+	val, err := store.Get(ctx, "c")
+	if err != nil || val != "3" {
+		t.Errorf("expected c to be kept but got %q, %v", val, err)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, advance := newMemoryStore("1h")
+	store.Set(ctx, "a", "1")
+	store.AddSessionID(ctx, 1, "a")
+	advance(30 * time.Minute)
+	store.Set(ctx, "b", "2")
+	store.AddSessionID(ctx, 1, "b")
+
+	advance(45 * time.Minute)
+	swept := store.Sweep()
+	if swept != 1 {
+		t.Errorf("expected 1 session to be swept but got %d", swept)
+	}
+
+	ids, _ := store.GetSessionIDs(ctx, 1)
+	if !slices.Equal(ids, []string{"b"}) {
+		t.Errorf("expected only b to be left in the index but got %v", ids)
+	}
+}
+
+func TestMemoryStoreSweeper(t *testing.T) {
+	store, advance := newMemoryStore("1h")
+	store.Set(ctx, "a", "1")
+	advance(2 * time.Hour)
+
+	stop := store.StartSweeper(time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		left := len(store.entries)
+		store.mu.Unlock()
+		if left == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("expected the sweeper to remove the expired session")
+}
@@ -2,6 +2,9 @@ package sessions
 
 import (
 	"context"
+	"errors"
+	"messaging-application/servers/gateway/timeout"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -9,38 +12,102 @@ import (
 
 type RedisStore struct {
 	rdb *redis.Client
-	ctx context.Context
 	exp time.Duration
+
+	// Timeout bounds each call to Redis. Zero leaves calls bounded only
+	// by their context.
+	Timeout time.Duration
+	// IndexExpiration is how long a user's set of session IDs is kept
+	// after a session was last added to it. It should be at least as long
+	// as session tokens last, since reading a session extends the session
+	// but not the set. The set is never kept for less than the session
+	// expiration.
+	IndexExpiration time.Duration
 }
 
 func NewRedisStore(client *redis.Client, expiration string) RedisStore {
-	res := RedisStore{}
+	res := RedisStore{Timeout: DefaultTimeout, IndexExpiration: DefaultTokenLifetime}
 	res.rdb = client
-	res.ctx = context.Background()
 	res.exp, _ = time.ParseDuration(expiration)
 	return res
 }
 
-func (rs *RedisStore) Get(key string) (string, error) {
-	pipe := rs.rdb.Pipeline()
+func (rs *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
 
-	getResult := pipe.Get(rs.ctx, key)
-	pipe.Expire(rs.ctx, key, rs.exp).Err()
+	pipe := rs.rdb.Pipeline()
+	getResult := pipe.Get(ctx, key)
+	pipe.Expire(ctx, key, rs.exp)
+	_, err := pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
 
-	_, err := pipe.Exec(rs.ctx)
-	if err != nil && err.Error() != "redis: nil" {
+	// Check the GET itself, so that a missing key is never mistaken for
+	// an empty state
+	val, err := getResult.Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrStateNotFound
+	} else if err != nil {
 		return "", err
 	}
+	return val, nil
+}
 
-	val := getResult.Val()
+func (rs *RedisStore) Set(ctx context.Context, key string, value string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	return rs.rdb.Set(ctx, key, value, rs.exp).Err()
+}
 
-	return val, nil
+func (rs *RedisStore) Replace(ctx context.Context, key string, value string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	err := rs.rdb.SetArgs(ctx, key, value, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if errors.Is(err, redis.Nil) {
+		return ErrStateNotFound
+	}
+	return err
+}
+
+func (rs *RedisStore) Delete(ctx context.Context, key string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	deleted, err := rs.rdb.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrStateNotFound
+	}
+	return nil
+}
+
+// userSessionsKey is the key of the set of a user's session IDs. Session
+// IDs are URL-safe base64, so they can never contain the colon.
+func userSessionsKey(userID int) string {
+	return "sessions:user:" + strconv.Itoa(userID)
+}
+
+func (rs *RedisStore) AddSessionID(ctx context.Context, userID int, sessionID string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	pipe := rs.rdb.TxPipeline()
+	pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+	pipe.Expire(ctx, userSessionsKey(userID), max(rs.IndexExpiration, rs.exp))
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-func (rs *RedisStore) Set(key string, value string) error {
-	return rs.rdb.Set(rs.ctx, key, value, rs.exp).Err()
+func (rs *RedisStore) RemoveSessionID(ctx context.Context, userID int, sessionID string) error {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	return rs.rdb.SRem(ctx, userSessionsKey(userID), sessionID).Err()
 }
 
-func (rs *RedisStore) Delete(key string) error {
-	return rs.rdb.Del(rs.ctx, key).Err()
+func (rs *RedisStore) GetSessionIDs(ctx context.Context, userID int) ([]string, error) {
+	ctx, cancel := timeout.Context(ctx, rs.Timeout)
+	defer cancel()
+	return rs.rdb.SMembers(ctx, userSessionsKey(userID)).Result()
 }
@@ -3,6 +3,8 @@
 package sessions
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -25,15 +27,15 @@ var redisClient = getClient()
 
 func TestSetGet(t *testing.T) {
 	client := NewRedisStore(redisClient, "3s")
-	err := client.Set("key", "3")
+	err := client.Set(ctx, "key", "3")
 	if err != nil {
 		t.Error("error setting key to 3")
 	}
-	err = client.Set("key2", "5")
+	err = client.Set(ctx, "key2", "5")
 	if err != nil {
 		t.Error("error setting key2 to 5")
 	}
-	val, err := client.Get("key")
+	val, err := client.Get(ctx, "key")
 	if err != nil {
 		t.Error("error fetching key")
 	}
@@ -44,15 +46,15 @@ func TestSetGet(t *testing.T) {
 
 func TestSetOverride(t *testing.T) {
 	client := NewRedisStore(redisClient, "3s")
-	err := client.Set("key", "3")
+	err := client.Set(ctx, "key", "3")
 	if err != nil {
 		t.Error("error setting key to 3")
 	}
-	err = client.Set("key", "5")
+	err = client.Set(ctx, "key", "5")
 	if err != nil {
 		t.Error("error setting key to 5")
 	}
-	val, err := client.Get("key")
+	val, err := client.Get(ctx, "key")
 	if err != nil {
 		t.Error("error fetching key")
 	}
@@ -63,29 +65,29 @@ func TestSetOverride(t *testing.T) {
 
 func TestExpiration(t *testing.T) {
 	client := NewRedisStore(redisClient, "2s")
-	err := client.Set("k", "3")
+	err := client.Set(ctx, "k", "3")
 	if err != nil {
 		t.Error("error setting k to 3")
 	}
 	duration, _ := time.ParseDuration("3s")
 	time.Sleep(duration)
-	_, err = client.Get("k")
-	if err != nil {
-		t.Errorf("error fetching key k: %s", err)
+	_, err = client.Get(ctx, "k")
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected expired key k to be not found, got: %v", err)
 	}
 }
 
 func TestReset(t *testing.T) {
 	client := NewRedisStore(redisClient, "10s")
-	err := client.Set("i", "3")
+	err := client.Set(ctx, "i", "3")
 	if err != nil {
 		t.Error("error setting i to 3")
 	}
 	duration, _ := time.ParseDuration("5s")
 	time.Sleep(duration)
-	client.Get("i")
+	client.Get(ctx, "i")
 	time.Sleep(duration)
-	val, err := client.Get("i")
+	val, err := client.Get(ctx, "i")
 	if err != nil {
 		t.Errorf("error fetching key i: %s", err)
 	}
@@ -96,3 +98,66 @@ func TestReset(t *testing.T) {
 		t.Error("key k should have equaled 3")
 	}
 }
+
+func TestSessionIDs(t *testing.T) {
+	client := NewRedisStore(redisClient, "10s")
+	err := client.AddSessionID(ctx, 42, "a")
+	if err != nil {
+		t.Errorf("error adding session ID: %s", err)
+	}
+	err = client.AddSessionID(ctx, 42, "b")
+	if err != nil {
+		t.Errorf("error adding session ID: %s", err)
+	}
+	err = client.RemoveSessionID(ctx, 42, "a")
+	if err != nil {
+		t.Errorf("error removing session ID: %s", err)
+	}
+	ids, err := client.GetSessionIDs(ctx, 42)
+	if err != nil {
+		t.Errorf("error getting session IDs: %s", err)
+	}
+	if len(ids) != 1 || ids[0] != "b" {
+		t.Errorf("expected session IDs [b] but got %v", ids)
+	}
+	client.RemoveSessionID(ctx, 42, "b")
+}
+
+func TestCancelledContext(t *testing.T) {
+	client := NewRedisStore(redisClient, "10s")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := client.Get(cancelled, "key")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	client := NewRedisStore(redisClient, "10s")
+	client.Timeout = time.Nanosecond
+
+	err := client.Set(ctx, "key", "3")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestSessionIDsExpire(t *testing.T) {
+	client := NewRedisStore(redisClient, "10s")
+	client.IndexExpiration = time.Minute
+	err := client.AddSessionID(ctx, 43, "a")
+	if err != nil {
+		t.Fatalf("error adding session ID: %s", err)
+	}
+	defer client.RemoveSessionID(ctx, 43, "a")
+
+	ttl, err := redisClient.TTL(ctx, userSessionsKey(43)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 50*time.Second || ttl > time.Minute {
+		t.Errorf("expected the session index to expire in a minute but got %s", ttl)
+	}
+}
@@ -1,44 +1,103 @@
 package sessions
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 const SESSIONID_LENGTH = 32
 
-func BeginSession(sessionState string, secret string, store Store) (string, error) {
-	sessionToken, sessionID, err := createSessionToken(secret, SESSIONID_LENGTH)
+func BeginSession(ctx context.Context, userID int, sessionState string, keys *Keyring, store Store) (string, error) {
+	sessionToken, sessionID, err := createSessionToken(keys, SESSIONID_LENGTH)
 	if err != nil {
 		return "", err
 	}
-	err = store.Set(sessionID, sessionState)
+	err = store.Set(ctx, sessionID, sessionState)
+	if err != nil {
+		return "", err
+	}
+	err = store.AddSessionID(ctx, userID, sessionID)
 	if err != nil {
 		return "", err
 	}
 	return sessionToken, nil
 }
 
-func GetSessionState(sessionToken string, secret string, store Store) (string, error) {
-	valid, sessionID, err := validToken(sessionToken, secret, SESSIONID_LENGTH)
+// GetSessionID returns the ID of the session a token refers to after
+// checking its signature against keys.
+func GetSessionID(sessionToken string, keys *Keyring) (string, error) {
+	valid, sessionID, err := validToken(sessionToken, keys, SESSIONID_LENGTH)
 	if err != nil {
 		return "", err
 	}
 	if !valid {
-		return "", errors.New("invalid session token")
+		return "", ErrInvalidID
 	}
-	userID, err := store.Get(sessionID)
+	return sessionID, nil
+}
+
+func GetSessionState(ctx context.Context, sessionToken string, keys *Keyring, store Store) (string, error) {
+	sessionID, err := GetSessionID(sessionToken, keys)
+	if err != nil {
+		return "", err
+	}
+	userID, err := store.Get(ctx, sessionID)
 	if err != nil {
 		return "", err
 	}
 	return userID, nil
 }
 
-func EndSession(sessionToken string, store Store) error {
+func EndSession(ctx context.Context, userID int, sessionToken string, store Store) error {
 	sessionID, err := extractIDFromToken(sessionToken, SESSIONID_LENGTH)
 	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	return RevokeSession(ctx, userID, sessionID, store)
+}
+
+// RevokeSession ends the user's session with the given ID. Sessions that
+// have already expired are only removed from the user's index.
+func RevokeSession(ctx context.Context, userID int, sessionID string, store Store) error {
+	err := store.Delete(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrStateNotFound) {
 		return err
 	}
-	err = store.Delete(sessionID)
+	return store.RemoveSessionID(ctx, userID, sessionID)
+}
+
+// EndAllSessions ends every session the user has, signing them out on
+// all devices.
+func EndAllSessions(ctx context.Context, userID int, store Store) error {
+	sessionIDs, err := store.GetSessionIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		err = RevokeSession(ctx, userID, sessionID, store)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EndOtherSessions ends every session the user has except the one with
+// the given ID, for example after they change their password.
+func EndOtherSessions(ctx context.Context, userID int, keepID string, store Store) error {
+	sessionIDs, err := store.GetSessionIDs(ctx, userID)
 	if err != nil {
 		return err
 	}
+	for _, sessionID := range sessionIDs {
+		if sessionID == keepID {
+			continue
+		}
+		err = RevokeSession(ctx, userID, sessionID, store)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,159 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var ctx = context.Background()
+
+var keys = newKeyring(Key{Secret: "c2VjcmV0"})
+
+func newKeyring(current Key, previous ...Key) *Keyring {
+	keys, err := NewKeyring(current, previous)
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	store := NewMemoryStore("1h")
+
+	token, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := GetSessionState(ctx, token, keys, store)
+	if err != nil {
+		t.Errorf("error getting session state: %s", err)
+	}
+	if state != "state" {
+		t.Errorf("expected state `state` but got `%s`", state)
+	}
+
+	err = EndSession(ctx, 1, token, store)
+	if err != nil {
+		t.Errorf("error ending session: %s", err)
+	}
+
+	_, err = GetSessionState(ctx, token, keys, store)
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound after ending session, got: %v", err)
+	}
+
+	ids, err := store.GetSessionIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("expected no session IDs after ending session, got %v", ids)
+	}
+}
+
+func TestEndAllSessions(t *testing.T) {
+	store := NewMemoryStore("1h")
+
+	var tokens []string
+	for i := 0; i < 3; i++ {
+		token, err := BeginSession(ctx, 1, "state", keys, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	other, err := BeginSession(ctx, 2, "other", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := store.GetSessionIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 {
+		t.Errorf("expected 3 session IDs but got %d", len(ids))
+	}
+
+	err = EndAllSessions(ctx, 1, store)
+	if err != nil {
+		t.Fatalf("error ending all sessions: %s", err)
+	}
+
+	for _, token := range tokens {
+		_, err = GetSessionState(ctx, token, keys, store)
+		if !errors.Is(err, ErrStateNotFound) {
+			t.Errorf("expected ErrStateNotFound after ending all sessions, got: %v", err)
+		}
+	}
+	ids, err = store.GetSessionIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("expected no session IDs after ending all sessions, got %v", ids)
+	}
+
+	_, err = GetSessionState(ctx, other, keys, store)
+	if err != nil {
+		t.Errorf("expected another user's session to survive, got: %v", err)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	store := NewMemoryStore("1h")
+
+	token, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = GetSessionState(ctx, token, newKeyring(Key{Secret: "b3RoZXI="}), store)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a token signed with another key, got: %v", err)
+	}
+
+	_, err = GetSessionState(ctx, "not base64!", keys, store)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for a malformed token, got: %v", err)
+	}
+
+	err = EndSession(ctx, 1, "not base64!", store)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID when ending a malformed token, got: %v", err)
+	}
+}
+
+func TestEndOtherSessions(t *testing.T) {
+	store := NewMemoryStore("1h")
+
+	keep, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keepID, err := GetSessionID(keep, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = EndOtherSessions(ctx, 1, keepID, store)
+	if err != nil {
+		t.Fatalf("error ending other sessions: %s", err)
+	}
+
+	_, err = GetSessionState(ctx, keep, keys, store)
+	if err != nil {
+		t.Errorf("expected kept session to survive, got: %v", err)
+	}
+	_, err = GetSessionState(ctx, other, keys, store)
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound for other session, got: %v", err)
+	}
+}
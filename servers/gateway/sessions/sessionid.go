@@ -5,42 +5,107 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
 )
 
-func createSessionToken(secret string, IDLength int) (string, string, error) {
-	id, err := generateRandomBytes(IDLength); if err != nil {
-		return "", "", err
-	}
-	secretBytes, err := base64.URLEncoding.DecodeString(secret)
+// tokenVersion is the version of the token format written by
+// createSessionToken. A version 1 token is the version, the ID of the
+// signing key, when the token expires in big-endian Unix seconds, the
+// session ID and an HMAC-SHA256 of everything before it, keyed with the
+// key's secret for sessions. Tokens from before versioning are the session
+// ID followed by its HMAC-SHA256, are verified with key 0 itself, and are
+// only accepted until the keyring's LegacyUntil.
+const tokenVersion = 1
+
+// tokenHeaderLength is the length of the version, key ID and expiration.
+const tokenHeaderLength = 10
+
+func createSessionToken(keys *Keyring, IDLength int) (string, string, error) {
+	id, err := generateRandomBytes(IDLength)
 	if err != nil {
 		return "", "", err
 	}
-	signature, err := signID(id, secretBytes); if err != nil {
+	current := keys.Current()
+	secret, _ := keys.secret(current.ID)
+	secret = derive(secret, sessionPurpose)
+
+	token := []byte{tokenVersion, current.ID}
+	token = binary.BigEndian.AppendUint64(token, uint64(keys.now().Add(keys.TokenLifetime).Unix()))
+	token = append(token, id...)
+	signature, err := signID(token, secret)
+	if err != nil {
 		return "", "", err
 	}
-	token := append(id, signature...)
+	token = append(token, signature...)
 	return encode(token), encode(id), nil
 }
 
-func validToken(sessionToken string, secret string, IDLength int) (bool, string, error) {
+// parsedToken is a decoded session token.
+type parsedToken struct {
+	// signed is the part of the token the signature covers
+	signed    []byte
+	keyID     byte
+	id        []byte
+	signature []byte
+	// expires is zero for tokens from before versioning
+	expires time.Time
+}
+
+func parseToken(tokenBytes []byte, IDLength int) (*parsedToken, error) {
+	switch len(tokenBytes) {
+	case tokenHeaderLength + IDLength + sha256.Size:
+		if tokenBytes[0] != tokenVersion {
+			return nil, fmt.Errorf("%w: unknown token version %d", ErrInvalidID, tokenBytes[0])
+		}
+		signed := tokenBytes[:tokenHeaderLength+IDLength]
+		return &parsedToken{
+			signed:    signed,
+			keyID:     tokenBytes[1],
+			id:        signed[tokenHeaderLength:],
+			signature: tokenBytes[len(signed):],
+			expires:   time.Unix(int64(binary.BigEndian.Uint64(tokenBytes[2:tokenHeaderLength])), 0),
+		}, nil
+	case IDLength + sha256.Size:
+		id := tokenBytes[:IDLength]
+		return &parsedToken{signed: id, id: id, signature: tokenBytes[IDLength:]}, nil
+	default:
+		return nil, fmt.Errorf("%w: token is %d bytes long", ErrInvalidID, len(tokenBytes))
+	}
+}
+
+func validToken(sessionToken string, keys *Keyring, IDLength int) (bool, string, error) {
 	tokenBytes, err := base64.URLEncoding.DecodeString(sessionToken)
 	if err != nil {
-		return false, "", err
+		return false, "", fmt.Errorf("%w: %v", ErrInvalidID, err)
 	}
-	sessionID := tokenBytes[:IDLength]
-	signature := tokenBytes[IDLength:]
-	secretBytes, err := base64.URLEncoding.DecodeString(secret)
+	token, err := parseToken(tokenBytes, IDLength)
 	if err != nil {
 		return false, "", err
 	}
-	signatureToCompare, err := signID(sessionID, secretBytes)
+	legacy := token.expires.IsZero()
+	if legacy && !keys.acceptsLegacy() {
+		return false, "", fmt.Errorf("%w: tokens from before versioning are no longer accepted", ErrInvalidID)
+	}
+	secret, ok := keys.secret(token.keyID)
+	if !ok {
+		return false, "", nil
+	}
+	if !legacy {
+		secret = derive(secret, sessionPurpose)
+	}
+	signatureToCompare, err := signID(token.signed, secret)
 	if err != nil {
 		return false, "", err
 	}
-	if !hmac.Equal(signature, signatureToCompare) {
+	if !hmac.Equal(token.signature, signatureToCompare) {
 		return false, "", nil
 	}
-	return true, encode(sessionID), nil
+	if !legacy && !keys.now().Before(token.expires) {
+		return false, "", fmt.Errorf("%w: token has expired", ErrInvalidID)
+	}
+	return true, encode(token.id), nil
 }
 
 func extractIDFromToken(sessionToken string, IDLength int) (string, error) {
@@ -48,13 +113,17 @@ func extractIDFromToken(sessionToken string, IDLength int) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	sessionID := tokenBytes[:IDLength]
-	return encode(sessionID), nil
+	token, err := parseToken(tokenBytes, IDLength)
+	if err != nil {
+		return "", err
+	}
+	return encode(token.id), nil
 }
 
 func generateRandomBytes(length int) ([]byte, error) {
 	bytes := make([]byte, length)
-	_, err := rand.Read(bytes); if err != nil {
+	_, err := rand.Read(bytes)
+	if err != nil {
 		return nil, err
 	}
 	return bytes, nil
@@ -62,7 +131,8 @@ func generateRandomBytes(length int) ([]byte, error) {
 
 func signID(id []byte, secret []byte) ([]byte, error) {
 	h := hmac.New(sha256.New, secret)
-	_, err := h.Write(id); if err != nil {
+	_, err := h.Write(id)
+	if err != nil {
 		return nil, err
 	}
 	return h.Sum(nil), nil
@@ -70,4 +140,4 @@ func signID(id []byte, secret []byte) ([]byte, error) {
 
 func encode(token []byte) string {
 	return base64.URLEncoding.EncodeToString(token)
-}
\ No newline at end of file
+}
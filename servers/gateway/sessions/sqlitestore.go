@@ -0,0 +1,181 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"messaging-application/servers/gateway/timeout"
+	"time"
+)
+
+// SQLiteStore keeps session states in a SQLite database, for gateways that
+// run without Redis. Like RedisStore, reading a session extends its
+// expiration. Expired sessions are removed by Sweep.
+type SQLiteStore struct {
+	db  *sql.DB
+	exp time.Duration
+	now func() time.Time
+
+	// Timeout bounds each call to the database. Zero leaves calls bounded
+	// only by their context.
+	Timeout time.Duration
+}
+
+// NewSQLiteStore returns a store in db, whose tables are created by the
+// SQLite migrations.
+func NewSQLiteStore(db *sql.DB, expiration string) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db must not be nil")
+	}
+
+	store := &SQLiteStore{db: db, now: time.Now, Timeout: DefaultTimeout}
+	store.exp, _ = time.ParseDuration(expiration)
+	return store, nil
+}
+
+// expiresAt returns when a session used now expires, in Unix nanoseconds.
+func (s *SQLiteStore) expiresAt() int64 {
+	return s.now().Add(s.exp).UnixNano()
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	uq := "UPDATE sessions SET expires_at = ? WHERE id = ? AND expires_at > ? RETURNING state"
+	var state string
+	err := s.db.QueryRowContext(ctx, uq, s.expiresAt(), key, s.now().UnixNano()).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrStateNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+func (s *SQLiteStore) Set(ctx context.Context, key string, value string) error {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	sq := "INSERT INTO sessions (id, state, expires_at) VALUES (?, ?, ?) " +
+		"ON CONFLICT (id) DO UPDATE SET state = excluded.state, expires_at = excluded.expires_at"
+	_, err := s.db.ExecContext(ctx, sq, key, value, s.expiresAt())
+	return err
+}
+
+func (s *SQLiteStore) Replace(ctx context.Context, key string, value string) error {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE sessions SET state = ? WHERE id = ? AND expires_at > ?", value, key, s.now().UnixNano())
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrStateNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, key string) error {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ? AND expires_at > ?", key, s.now().UnixNano())
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrStateNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddSessionID(ctx context.Context, userID int, sessionID string) error {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "INSERT OR IGNORE INTO user_sessions (user_id, session_id) VALUES (?, ?)", userID, sessionID)
+	return err
+}
+
+func (s *SQLiteStore) RemoveSessionID(ctx context.Context, userID int, sessionID string) error {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM user_sessions WHERE user_id = ? AND session_id = ?", userID, sessionID)
+	return err
+}
+
+func (s *SQLiteStore) GetSessionIDs(ctx context.Context, userID int) ([]string, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT session_id FROM user_sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Sweep deletes expired sessions and removes them from their users'
+// indexes. It returns how many sessions were deleted.
+func (s *SQLiteStore) Sweep(ctx context.Context) (int64, error) {
+	ctx, cancel := timeout.Context(ctx, s.Timeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", s.now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM user_sessions WHERE session_id NOT IN (SELECT id FROM sessions)")
+	return deleted, err
+}
+
+// StartSweeper calls Sweep every interval until the returned function is
+// called.
+func (s *SQLiteStore) StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := s.Sweep(context.Background())
+				if err != nil {
+					log.Printf("error sweeping expired sessions: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,147 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"messaging-application/servers/gateway/migrations"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// openMigratedDB returns a new SQLite database with every migration
+// applied.
+func openMigratedDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("error opening db: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	all, err := migrations.Embedded(migrations.SQLite)
+	if err != nil {
+		t.Fatalf("error loading migrations: %s", err)
+	}
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("error migrating db: %s", err)
+	}
+	return db
+}
+
+// newSQLiteStore returns a store in a new database whose clock is
+// controlled by the returned function.
+func newSQLiteStore(t *testing.T) (*SQLiteStore, func(time.Duration)) {
+	store, err := NewSQLiteStore(openMigratedDB(t), "1h")
+	if err != nil {
+		t.Fatalf("error creating store: %s", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSQLiteSetGetDelete(t *testing.T) {
+	store, _ := newSQLiteStore(t)
+
+	_, err := store.Get(ctx, "a")
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound for a missing key but got %v", err)
+	}
+
+	store.Set(ctx, "a", "1")
+	store.Set(ctx, "a", "2")
+	val, err := store.Get(ctx, "a")
+	if err != nil || val != "2" {
+		t.Errorf("expected 2 but got %q, %v", val, err)
+	}
+
+	err = store.Delete(ctx, "a")
+	if err != nil {
+		t.Errorf("unexpected error deleting: %s", err)
+	}
+	err = store.Delete(ctx, "a")
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound deleting twice but got %v", err)
+	}
+}
+
+func TestSQLiteExpiration(t *testing.T) {
+	store, advance := newSQLiteStore(t)
+	store.Set(ctx, "a", "1")
+	store.Set(ctx, "b", "2")
+
+	// Reading a session slides its expiration forward
+	advance(50 * time.Minute)
+	store.Get(ctx, "a")
+	advance(50 * time.Minute)
+
+	_, err := store.Get(ctx, "a")
+	if err != nil {
+		t.Errorf("expected the refreshed session to be alive but got %v", err)
+	}
+	_, err = store.Get(ctx, "b")
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected the idle session to have expired but got %v", err)
+	}
+}
+
+func TestSQLiteSweep(t *testing.T) {
+	store, advance := newSQLiteStore(t)
+	store.Set(ctx, "a", "1")
+	store.AddSessionID(ctx, 1, "a")
+	advance(30 * time.Minute)
+	store.Set(ctx, "b", "2")
+	store.AddSessionID(ctx, 1, "b")
+
+	advance(45 * time.Minute)
+	swept, err := store.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("error sweeping: %s", err)
+	}
+	if swept != 1 {
+		t.Errorf("expected 1 session to be swept but got %d", swept)
+	}
+
+	ids, err := store.GetSessionIDs(ctx, 1)
+	if err != nil {
+		t.Fatalf("error getting session IDs: %s", err)
+	}
+	if !slices.Equal(ids, []string{"b"}) {
+		t.Errorf("expected only b to be left in the index but got %v", ids)
+	}
+}
+
+func TestSQLiteSessionLifecycle(t *testing.T) {
+	store, _ := newSQLiteStore(t)
+
+	token, err := BeginSession(ctx, 1, "state", keys, store)
+	if err != nil {
+		t.Fatalf("error beginning session: %s", err)
+	}
+	state, err := GetSessionState(ctx, token, keys, store)
+	if err != nil || state != "state" {
+		t.Errorf("expected state but got %q, %v", state, err)
+	}
+
+	err = EndAllSessions(ctx, 1, store)
+	if err != nil {
+		t.Fatalf("error ending sessions: %s", err)
+	}
+	_, err = GetSessionState(ctx, token, keys, store)
+	if !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected the session to be gone but got %v", err)
+	}
+	ids, _ := store.GetSessionIDs(ctx, 1)
+	if len(ids) != 0 {
+		t.Errorf("expected an empty index but got %v", ids)
+	}
+}
@@ -1,7 +1,44 @@
 package sessions
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStateNotFound is returned when no session state is stored for an ID,
+// because it never existed, has expired, or has ended.
+var ErrStateNotFound = errors.New("no session state found for the given ID")
+
+// ErrInvalidID is returned when a session token is malformed or its
+// signature does not match.
+var ErrInvalidID = errors.New("invalid session ID")
+
+// DefaultTimeout is how long a RedisStore or SQLiteStore waits for each
+// call by default.
+const DefaultTimeout = 3 * time.Second
+
+// Store keeps session states until they go unused for the store's
+// expiration. The storetest package checks that a Store behaves this way.
 type Store interface {
-	Get(key string) (string, error)
-	Set(key string, value string) error
-	Delete(key string) error
+	// Get returns the state stored for key and restarts its expiration,
+	// or returns ErrStateNotFound if there is none or it has expired.
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value for key, replacing any earlier state and restarting
+	// its expiration.
+	Set(ctx context.Context, key string, value string) error
+	// Replace stores value for key only if a state is still stored for it,
+	// keeping its expiration, or returns ErrStateNotFound if there is
+	// none. Unlike Set, it cannot bring back a state that has just been
+	// deleted.
+	Replace(ctx context.Context, key string, value string) error
+	// Delete removes the state stored for key, or returns ErrStateNotFound
+	// if there is none.
+	Delete(ctx context.Context, key string) error
+
+	// AddSessionID, RemoveSessionID and GetSessionIDs maintain the index
+	// of each user's active session IDs.
+	AddSessionID(ctx context.Context, userID int, sessionID string) error
+	RemoveSessionID(ctx context.Context, userID int, sessionID string) error
+	GetSessionIDs(ctx context.Context, userID int) ([]string, error)
 }
@@ -0,0 +1,28 @@
+//go:build !no_db
+
+package sessions_test
+
+import (
+	"messaging-application/servers/gateway/sessions"
+	"messaging-application/servers/gateway/sessions/storetest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStoreConformance(t *testing.T) {
+	addr := os.Getenv("REDISADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+
+	// Redis expires keys on its own clock, so time is advanced by sleeping
+	storetest.Run(t, func(t *testing.T, expiration time.Duration) (sessions.Store, func(time.Duration)) {
+		store := sessions.NewRedisStore(client, expiration.String())
+		return &store, time.Sleep
+	})
+}
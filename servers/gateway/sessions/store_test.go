@@ -0,0 +1,36 @@
+package sessions_test
+
+import (
+	"messaging-application/servers/gateway/sessions"
+	"messaging-application/servers/gateway/sessions/storetest"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock for a store and a function that moves it
+// forward.
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, expiration time.Duration) (sessions.Store, func(time.Duration)) {
+		store := sessions.NewMemoryStore(expiration.String())
+		now, advance := fakeClock()
+		sessions.SetClock(store, now)
+		return store, advance
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, expiration time.Duration) (sessions.Store, func(time.Duration)) {
+		store, err := sessions.NewSQLiteStore(sessions.OpenMigratedDB(t), expiration.String())
+		if err != nil {
+			t.Fatalf("Error creating store: %s", err)
+		}
+		now, advance := fakeClock()
+		sessions.SetClock(store, now)
+		return store, advance
+	})
+}
@@ -0,0 +1,230 @@
+// Package storetest is a conformance test suite for implementations of
+// sessions.Store, so that sessions behave the same whichever store keeps
+// them.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"messaging-application/servers/gateway/sessions"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Expiration is the expiration the suite asks stores for. It is short
+// enough for stores on a real clock to advance it by sleeping.
+const Expiration = 2 * time.Second
+
+// userID and otherUserID are unlikely to collide with real users of a
+// store shared with other tests.
+const (
+	userID      = 900001
+	otherUserID = 900002
+)
+
+// NewStore returns a new store whose states expire after expiration,
+// along with a function that moves the store's clock forward.
+type NewStore func(t *testing.T, expiration time.Duration) (sessions.Store, func(d time.Duration))
+
+// Run runs every conformance test against stores made by newStore.
+func Run(t *testing.T, newStore NewStore) {
+	tests := map[string]func(t *testing.T, store sessions.Store, advance func(time.Duration)){
+		"GetMissing":     testGetMissing,
+		"SetGet":         testSetGet,
+		"Overwrite":      testOverwrite,
+		"Replace":        testReplace,
+		"ReplaceMissing": testReplaceMissing,
+		"Delete":         testDelete,
+		"DeleteMissing":  testDeleteMissing,
+		"Expiry":         testExpiry,
+		"SlidingRefresh": testSlidingRefresh,
+		"SessionIDs":     testSessionIDs,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, advance := newStore(t, Expiration)
+			test(t, store, advance)
+		})
+	}
+}
+
+// key returns a key that is unique to the running test, so that stores
+// shared between tests do not see each other's states.
+func key(t *testing.T, name string) string {
+	return "storetest:" + t.Name() + ":" + name
+}
+
+// set stores value for name, failing the test on any error.
+func set(t *testing.T, store sessions.Store, name string, value string) {
+	t.Helper()
+	err := store.Set(context.Background(), key(t, name), value)
+	if err != nil {
+		t.Fatalf("Error setting %s: %s", name, err)
+	}
+	t.Cleanup(func() { store.Delete(context.Background(), key(t, name)) })
+}
+
+// checkValue fails the test unless name holds expected.
+func checkValue(t *testing.T, store sessions.Store, name string, expected string) {
+	t.Helper()
+	val, err := store.Get(context.Background(), key(t, name))
+	if err != nil {
+		t.Errorf("Error getting %s: %s", name, err)
+	} else if val != expected {
+		t.Errorf("Expected %s to be %q but got %q", name, expected, val)
+	}
+}
+
+// checkMissing fails the test unless name holds nothing.
+func checkMissing(t *testing.T, store sessions.Store, name string) {
+	t.Helper()
+	val, err := store.Get(context.Background(), key(t, name))
+	if !errors.Is(err, sessions.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound for %s but got %q, %v", name, val, err)
+	}
+}
+
+func testGetMissing(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	checkMissing(t, store, "missing")
+}
+
+func testSetGet(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "a", "1")
+	set(t, store, "b", "2")
+	checkValue(t, store, "a", "1")
+	checkValue(t, store, "b", "2")
+}
+
+func testOverwrite(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "a", "1")
+	set(t, store, "a", "2")
+	checkValue(t, store, "a", "2")
+}
+
+func testReplace(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "a", "1")
+	set(t, store, "b", "2")
+
+	advance(Expiration / 2)
+	for _, name := range []string{"a", "b"} {
+		err := store.Replace(context.Background(), key(t, name), "3")
+		if err != nil {
+			t.Fatalf("Error replacing %s: %s", name, err)
+		}
+	}
+	checkValue(t, store, "a", "3")
+
+	// Replacing b did not restart its expiration
+	advance(Expiration * 3 / 4)
+	checkMissing(t, store, "b")
+}
+
+func testReplaceMissing(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "deleted", "1")
+	set(t, store, "expired", "2")
+	err := store.Delete(context.Background(), key(t, "deleted"))
+	if err != nil {
+		t.Fatalf("Error deleting: %s", err)
+	}
+	advance(Expiration * 3 / 2)
+
+	for _, name := range []string{"missing", "deleted", "expired"} {
+		err = store.Replace(context.Background(), key(t, name), "3")
+		if !errors.Is(err, sessions.ErrStateNotFound) {
+			t.Errorf("Expected ErrStateNotFound replacing %s but got %v", name, err)
+		}
+		checkMissing(t, store, name)
+	}
+}
+
+func testDelete(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "a", "1")
+	set(t, store, "b", "2")
+
+	err := store.Delete(context.Background(), key(t, "a"))
+	if err != nil {
+		t.Fatalf("Error deleting a: %s", err)
+	}
+	checkMissing(t, store, "a")
+	checkValue(t, store, "b", "2")
+}
+
+func testDeleteMissing(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	err := store.Delete(context.Background(), key(t, "missing"))
+	if !errors.Is(err, sessions.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound but got %v", err)
+	}
+}
+
+func testExpiry(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "a", "1")
+
+	advance(Expiration / 2)
+	checkValue(t, store, "a", "1")
+
+	advance(Expiration * 3 / 2)
+	checkMissing(t, store, "a")
+
+	err := store.Delete(context.Background(), key(t, "a"))
+	if !errors.Is(err, sessions.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound deleting an expired state but got %v", err)
+	}
+}
+
+func testSlidingRefresh(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	set(t, store, "read", "1")
+	set(t, store, "idle", "2")
+
+	// Reading a state restarts its expiration, so read stays alive well
+	// past the expiration of idle
+	for range 3 {
+		advance(Expiration / 2)
+		checkValue(t, store, "read", "1")
+	}
+	checkMissing(t, store, "idle")
+
+	advance(Expiration * 3 / 2)
+	checkMissing(t, store, "read")
+}
+
+func testSessionIDs(t *testing.T, store sessions.Store, advance func(time.Duration)) {
+	ctx := context.Background()
+	ids := []string{key(t, "a"), key(t, "b"), key(t, "c")}
+	for _, id := range ids {
+		err := store.AddSessionID(ctx, userID, id)
+		if err != nil {
+			t.Fatalf("Error adding session ID: %s", err)
+		}
+		t.Cleanup(func() { store.RemoveSessionID(ctx, userID, id) })
+	}
+	// Adding an ID twice keeps a single copy
+	store.AddSessionID(ctx, userID, ids[0])
+
+	err := store.RemoveSessionID(ctx, userID, ids[1])
+	if err != nil {
+		t.Fatalf("Error removing session ID: %s", err)
+	}
+	err = store.RemoveSessionID(ctx, userID, key(t, "missing"))
+	if err != nil {
+		t.Errorf("Expected removing a missing session ID to succeed but got %s", err)
+	}
+
+	found, err := store.GetSessionIDs(ctx, userID)
+	if err != nil {
+		t.Fatalf("Error getting session IDs: %s", err)
+	}
+	slices.Sort(found)
+	if !slices.Equal(found, []string{ids[0], ids[2]}) {
+		t.Errorf("Expected session IDs %v but got %v", []string{ids[0], ids[2]}, found)
+	}
+
+	found, err = store.GetSessionIDs(ctx, otherUserID)
+	if err != nil {
+		t.Fatalf("Error getting session IDs: %s", err)
+	}
+	if found == nil || len(found) != 0 {
+		t.Errorf("Expected no session IDs for another user but got %v", found)
+	}
+}
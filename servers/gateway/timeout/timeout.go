@@ -0,0 +1,18 @@
+// Package timeout bounds the calls that stores make to Redis and the
+// database, so that a slow backend cannot hold up a request forever.
+package timeout
+
+import (
+	"context"
+	"time"
+)
+
+// Context returns a copy of ctx that is done after d, along with the
+// function that releases it. When d is not positive, ctx is returned as it
+// is, so that a zero timeout means no bound.
+func Context(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
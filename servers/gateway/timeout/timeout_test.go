@@ -0,0 +1,27 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	ctx, cancel := Context(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("Expected a deadline within a minute, got %v, %v", deadline, ok)
+	}
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		ctx, cancel = Context(context.Background(), d)
+		cancel()
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("Expected no deadline for %s", d)
+		}
+		if ctx.Err() != nil {
+			t.Errorf("Expected cancel to leave ctx alone for %s, got %v", d, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,4 @@
+GOOS=linux go build
+docker build -t rjames187/messaging:1.0 .
+go clean
+docker push rjames187/messaging:1.0
@@ -0,0 +1,2 @@
+go test ./... -coverprofile=coverage.out
+go tool cover -html=coverage.out
\ No newline at end of file
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ChannelNew    = "channel-new"
+	ChannelUpdate = "channel-update"
+	ChannelDelete = "channel-delete"
+	MessageNew    = "message-new"
+	MessageUpdate = "message-update"
+	MessageDelete = "message-delete"
+)
+
+// Event is a notification for the gateway to push to connected clients.
+// UserIDs lists the users allowed to see it; an empty list means everyone.
+type Event struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+	UserIDs []int  `json:"userIDs,omitempty"`
+}
+
+// DefaultTimeout is how long a RedisPublisher waits for each publish by
+// default.
+const DefaultTimeout = 3 * time.Second
+
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// RedisPublisher publishes events to a Redis pub/sub channel that every
+// gateway instance subscribes to.
+type RedisPublisher struct {
+	rdb     *redis.Client
+	channel string
+
+	// Timeout bounds each publish to Redis. Zero leaves publishes bounded
+	// only by their context.
+	Timeout time.Duration
+}
+
+func NewRedisPublisher(client *redis.Client, channel string) RedisPublisher {
+	return RedisPublisher{
+		rdb:     client,
+		channel: channel,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func (rp *RedisPublisher) Publish(ctx context.Context, event *Event) error {
+	if rp.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rp.Timeout)
+		defer cancel()
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return rp.rdb.Publish(ctx, rp.channel, message).Err()
+}
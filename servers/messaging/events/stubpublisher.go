@@ -0,0 +1,16 @@
+package events
+
+import "context"
+
+type StubPublisher struct {
+	Events []*Event
+}
+
+func NewStubPublisher() *StubPublisher {
+	return &StubPublisher{Events: []*Event{}}
+}
+
+func (s *StubPublisher) Publish(ctx context.Context, event *Event) error {
+	s.Events = append(s.Events, event)
+	return nil
+}
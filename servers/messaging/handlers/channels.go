@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"messaging-application/servers/messaging/events"
+	"messaging-application/servers/messaging/models/channels"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const messagesPageSize = 100
+
+type member struct {
+	ID int `json:"id"`
+}
+
+func (ctx *HandlerContext) ChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		allChannels, err := ctx.Store.GetChannels(user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(allChannels)
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		newChannel := &channels.NewChannel{}
+		err := json.NewDecoder(r.Body).Decode(newChannel)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		err = newChannel.Validate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		channel, err := ctx.Store.InsertChannel(newChannel.ToChannel(user.ID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.ChannelNew, channel, channel)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(channel)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *HandlerContext) SpecificChannelHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	channel, ok := ctx.getChannel(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !channel.CanView(user.ID) {
+			http.Error(w, "You are not a member of this channel", http.StatusForbidden)
+			return
+		}
+
+		before := 0
+		if beforeParam := r.URL.Query().Get("before"); beforeParam != "" {
+			before, err = strconv.Atoi(beforeParam)
+			if err != nil {
+				http.Error(w, "Invalid message ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		messages, err := ctx.Store.GetMessages(channel.ID, before, messagesPageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(messages)
+	case http.MethodPost:
+		if !channel.IsMember(user.ID) {
+			http.Error(w, "You are not a member of this channel", http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		newMessage := &channels.NewMessage{}
+		err := json.NewDecoder(r.Body).Decode(newMessage)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		err = newMessage.Validate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		message, err := ctx.Store.InsertMessage(newMessage.ToMessage(channel.ID, user.ID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.MessageNew, channel, message)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(message)
+	case http.MethodPatch:
+		if channel.CreatorID != user.ID {
+			http.Error(w, "You are not allowed to update this channel", http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		channelUpdates := &channels.ChannelUpdates{}
+		err := json.NewDecoder(r.Body).Decode(channelUpdates)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		err = channel.ApplyUpdates(channelUpdates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updatedChannel, err := ctx.Store.UpdateChannel(channel.ID, channel)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.ChannelUpdate, updatedChannel, updatedChannel)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(updatedChannel)
+	case http.MethodDelete:
+		if channel.CreatorID != user.ID {
+			http.Error(w, "You are not allowed to delete this channel", http.StatusForbidden)
+			return
+		}
+
+		err := ctx.Store.DeleteChannel(channel.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.ChannelDelete, channel, map[string]int{"id": channel.ID})
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *HandlerContext) MembersHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel, ok := ctx.getChannel(w, r)
+	if !ok {
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	target := &member{}
+	err = json.NewDecoder(r.Body).Decode(target)
+	if err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	// The creator manages membership; anyone else may only join a public
+	// channel or leave a channel themselves.
+	isSelf := target.ID == user.ID
+	if channel.CreatorID != user.ID && !(isSelf && (r.Method == http.MethodDelete || !channel.Private)) {
+		http.Error(w, "You are not allowed to change the members of this channel", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		err = ctx.Store.AddMember(channel.ID, target.ID)
+	} else {
+		if target.ID == channel.CreatorID {
+			http.Error(w, "The creator cannot leave their own channel", http.StatusBadRequest)
+			return
+		}
+		err = ctx.Store.RemoveMember(channel.ID, target.ID)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Member added"))
+	} else {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Member removed"))
+	}
+}
+
+func (ctx *HandlerContext) SpecificMessageHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messageID, err := strconv.Atoi(r.PathValue("MessageID"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	message, err := ctx.Store.GetMessage(messageID)
+	if errors.Is(err, channels.ErrMessageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if message.CreatorID != user.ID {
+		http.Error(w, "You are not allowed to modify this message", http.StatusForbidden)
+		return
+	}
+
+	channel, err := ctx.Store.GetChannel(message.ChannelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		messageUpdates := &channels.MessageUpdates{}
+		err := json.NewDecoder(r.Body).Decode(messageUpdates)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		err = message.ApplyUpdates(messageUpdates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updatedMessage, err := ctx.Store.UpdateMessage(messageID, message)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.MessageUpdate, channel, updatedMessage)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(updatedMessage)
+	case http.MethodDelete:
+		err := ctx.Store.DeleteMessage(messageID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx.notify(r, events.MessageDelete, channel, map[string]int{"id": messageID, "channelId": channel.ID})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getChannel looks up the channel named by the ChannelID path value,
+// writing an error response and returning false if it cannot be found.
+func (ctx *HandlerContext) getChannel(w http.ResponseWriter, r *http.Request) (*channels.Channel, bool) {
+	channelID, err := strconv.Atoi(r.PathValue("ChannelID"))
+	if err != nil {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	channel, err := ctx.Store.GetChannel(channelID)
+	if errors.Is(err, channels.ErrChannelNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return channel, true
+}
+
+// notify publishes an event about channel to the users allowed to see it,
+// for as long as r is not cancelled. Failures are only logged because the
+// change itself has already been saved.
+func (ctx *HandlerContext) notify(r *http.Request, eventType string, channel *channels.Channel, payload any) {
+	event := &events.Event{Type: eventType, Payload: payload}
+	if channel.Private {
+		event.UserIDs = channel.Members
+	}
+
+	err := ctx.Publisher.Publish(r.Context(), event)
+	if err != nil {
+		log.Printf("error publishing %s event: %v", eventType, err)
+	}
+}
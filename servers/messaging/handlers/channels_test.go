@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"messaging-application/servers/messaging/events"
+	"messaging-application/servers/messaging/models/channels"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var ctx *HandlerContext
+var publisher *events.StubPublisher
+
+func newContext() *http.ServeMux {
+	publisher = events.NewStubPublisher()
+	ctx = &HandlerContext{
+		channels.NewStubStore(),
+		publisher,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/channels", ctx.ChannelsHandler)
+	mux.HandleFunc("/v1/channels/{ChannelID}", ctx.SpecificChannelHandler)
+	mux.HandleFunc("/v1/channels/{ChannelID}/members", ctx.MembersHandler)
+	mux.HandleFunc("/v1/messages/{MessageID}", ctx.SpecificMessageHandler)
+	return mux
+}
+
+// newRequest builds a request as the gateway would forward it for the
+// user with the given ID, encoding body as JSON when it is not nil.
+func newRequest(t *testing.T, method string, target string, userID int, body any) *http.Request {
+	var req *http.Request
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = httptest.NewRequest(method, target, bytes.NewReader(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+
+	if userID > 0 {
+		user, err := json.Marshal(&User{ID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-User", string(user))
+	}
+	return req
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestChannelsFlow(t *testing.T) {
+	handler := newContext()
+
+	// Create a private channel with user 2 as a member
+	rr := serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{
+		Name:    "team",
+		Private: true,
+		Members: []int{2},
+	}))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+	if !strings.Contains(rr.Body.String(), `"id":1`) {
+		t.Error("Expected channel ID to be returned")
+	}
+
+	// Members and outsiders see different channel lists
+	rr = serve(handler, newRequest(t, http.MethodGet, "/v1/channels", 2, nil))
+	if !strings.Contains(rr.Body.String(), `"name":"team"`) {
+		t.Error("Expected member to see the private channel")
+	}
+	rr = serve(handler, newRequest(t, http.MethodGet, "/v1/channels", 3, nil))
+	if strings.Contains(rr.Body.String(), `"name":"team"`) {
+		t.Error("Expected non-member not to see the private channel")
+	}
+
+	// Post a message as a member
+	rr = serve(handler, newRequest(t, http.MethodPost, "/v1/channels/1", 2, &channels.NewMessage{Body: "hello"}))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	// Read the messages back
+	rr = serve(handler, newRequest(t, http.MethodGet, "/v1/channels/1", 1, nil))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if !strings.Contains(rr.Body.String(), `"body":"hello"`) {
+		t.Error("Expected message to be returned")
+	}
+
+	// Edit the message as its creator
+	rr = serve(handler, newRequest(t, http.MethodPatch, "/v1/messages/1", 2, &channels.MessageUpdates{Body: "hi"}))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if !strings.Contains(rr.Body.String(), `"body":"hi"`) {
+		t.Error("Expected updated message to be returned")
+	}
+
+	// Rename the channel as its creator
+	rr = serve(handler, newRequest(t, http.MethodPatch, "/v1/channels/1", 1, &channels.ChannelUpdates{Name: "crew"}))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if !strings.Contains(rr.Body.String(), `"name":"crew"`) {
+		t.Error("Expected updated channel to be returned")
+	}
+
+	// Delete the message, then the channel
+	rr = serve(handler, newRequest(t, http.MethodDelete, "/v1/messages/1", 2, nil))
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+	rr = serve(handler, newRequest(t, http.MethodDelete, "/v1/channels/1", 1, nil))
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+	rr = serve(handler, newRequest(t, http.MethodGet, "/v1/channels/1", 1, nil))
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestMembersFlow(t *testing.T) {
+	handler := newContext()
+
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{Name: "general"}))
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{Name: "team", Private: true}))
+
+	// Anyone may join a public channel
+	rr := serve(handler, newRequest(t, http.MethodPost, "/v1/channels/1/members", 2, &member{ID: 2}))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+	rr = serve(handler, newRequest(t, http.MethodPost, "/v1/channels/1", 2, &channels.NewMessage{Body: "hello"}))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+
+	// But not a private one
+	rr = serve(handler, newRequest(t, http.MethodPost, "/v1/channels/2/members", 2, &member{ID: 2}))
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, status)
+	}
+
+	// The creator can add and remove members of a private channel
+	rr = serve(handler, newRequest(t, http.MethodPost, "/v1/channels/2/members", 1, &member{ID: 2}))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+	}
+	rr = serve(handler, newRequest(t, http.MethodDelete, "/v1/channels/2/members", 1, &member{ID: 2}))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	rr = serve(handler, newRequest(t, http.MethodGet, "/v1/channels/2", 2, nil))
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, status)
+	}
+}
+
+func TestChannelsErrors(t *testing.T) {
+	handler := newContext()
+
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{Name: "general"}))
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels/1", 1, &channels.NewMessage{Body: "hello"}))
+
+	cases := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"missing X-User", newRequest(t, http.MethodGet, "/v1/channels", 0, nil), http.StatusUnauthorized},
+		{"wrong method", newRequest(t, http.MethodPut, "/v1/channels", 1, nil), http.StatusMethodNotAllowed},
+		{"blank channel name", newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{}), http.StatusBadRequest},
+		{"invalid channel ID", newRequest(t, http.MethodGet, "/v1/channels/abc", 1, nil), http.StatusBadRequest},
+		{"missing channel", newRequest(t, http.MethodGet, "/v1/channels/99", 1, nil), http.StatusNotFound},
+		{"non-member post", newRequest(t, http.MethodPost, "/v1/channels/1", 2, &channels.NewMessage{Body: "hi"}), http.StatusForbidden},
+		{"blank message", newRequest(t, http.MethodPost, "/v1/channels/1", 1, &channels.NewMessage{}), http.StatusBadRequest},
+		{"non-creator channel update", newRequest(t, http.MethodPatch, "/v1/channels/1", 2, &channels.ChannelUpdates{Name: "x"}), http.StatusForbidden},
+		{"non-creator channel delete", newRequest(t, http.MethodDelete, "/v1/channels/1", 2, nil), http.StatusForbidden},
+		{"non-creator message update", newRequest(t, http.MethodPatch, "/v1/messages/1", 2, &channels.MessageUpdates{Body: "x"}), http.StatusForbidden},
+		{"non-creator message delete", newRequest(t, http.MethodDelete, "/v1/messages/1", 2, nil), http.StatusForbidden},
+		{"missing message", newRequest(t, http.MethodDelete, "/v1/messages/99", 1, nil), http.StatusNotFound},
+		{"creator leaving", newRequest(t, http.MethodDelete, "/v1/channels/1/members", 1, &member{ID: 1}), http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		rr := serve(handler, c.req)
+		if status := rr.Code; status != c.status {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.status, status)
+		}
+	}
+
+	// Wrong content type
+	req := newRequest(t, http.MethodPost, "/v1/channels", 1, nil)
+	req.Header.Set("Content-Type", "text/plain")
+	rr := serve(handler, req)
+	if status := rr.Code; status != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, status)
+	}
+}
+
+func TestChannelEvents(t *testing.T) {
+	handler := newContext()
+
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{Name: "general"}))
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels", 1, &channels.NewChannel{Name: "team", Private: true, Members: []int{2}}))
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels/2", 2, &channels.NewMessage{Body: "hello"}))
+	serve(handler, newRequest(t, http.MethodPatch, "/v1/messages/1", 2, &channels.MessageUpdates{Body: "hi"}))
+	serve(handler, newRequest(t, http.MethodDelete, "/v1/messages/1", 2, nil))
+	serve(handler, newRequest(t, http.MethodDelete, "/v1/channels/2", 1, nil))
+
+	// Failed requests publish nothing
+	serve(handler, newRequest(t, http.MethodPost, "/v1/channels/2", 3, &channels.NewMessage{Body: "hello"}))
+
+	expected := []string{events.ChannelNew, events.ChannelNew, events.MessageNew, events.MessageUpdate, events.MessageDelete, events.ChannelDelete}
+	if len(publisher.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(publisher.Events))
+	}
+
+	for i, event := range publisher.Events {
+		if event.Type != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], event.Type)
+		}
+	}
+
+	if len(publisher.Events[0].UserIDs) != 0 {
+		t.Error("Expected public channel events to go to everyone")
+	}
+	for _, event := range publisher.Events[1:] {
+		if !slices.Equal(event.UserIDs, []int{1, 2}) {
+			t.Errorf("Expected %s event to go to members only, got %v", event.Type, event.UserIDs)
+		}
+	}
+}
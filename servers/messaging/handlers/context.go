@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"messaging-application/servers/messaging/events"
+	"messaging-application/servers/messaging/models/channels"
+)
+
+type HandlerContext struct {
+	Store     channels.Store   `json:"store"`
+	Publisher events.Publisher `json:"publisher"`
+}
+
+func NewHandlerContext(store channels.Store, publisher events.Publisher) *HandlerContext {
+	return &HandlerContext{
+		Store:     store,
+		Publisher: publisher,
+	}
+}
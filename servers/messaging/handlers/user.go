@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// User is the profile of the signed-in user that the gateway forwards
+// in the X-User header after authenticating the request.
+type User struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photoUrl"`
+}
+
+func getCurrentUser(r *http.Request) (*User, error) {
+	header := r.Header.Get("X-User")
+	if header == "" {
+		return nil, errors.New("missing X-User header")
+	}
+
+	user := &User{}
+	err := json.Unmarshal([]byte(header), user)
+	if err != nil {
+		return nil, errors.New("invalid X-User header")
+	}
+	if user.ID <= 0 {
+		return nil, errors.New("invalid X-User header")
+	}
+
+	return user, nil
+}
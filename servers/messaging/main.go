@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"messaging-application/servers/messaging/events"
+	"messaging-application/servers/messaging/handlers"
+	"messaging-application/servers/messaging/models/channels"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// startupAttempts is how many times each backend is pinged at startup.
+// The wait between attempts starts at a second and doubles each time, up
+// to maxStartupWait.
+const startupAttempts = 8
+const maxStartupWait = 16 * time.Second
+
+// waitFor pings a backend until it answers or the attempts run out, and
+// returns the last error.
+func waitFor(name string, ping func(context.Context) error) error {
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		err := ping(context.Background())
+		if err == nil || attempt >= startupAttempts {
+			return err
+		}
+		log.Printf("error pinging %s (attempt %d), retrying in %s: %v", name, attempt, wait, err)
+		time.Sleep(wait)
+		wait = min(wait*2, maxStartupWait)
+	}
+}
+
+func main() {
+	ADDR := os.Getenv("ADDR")
+	if len(ADDR) == 0 {
+		ADDR = ":80"
+	}
+
+	DSN := os.Getenv("DSN")
+	if len(DSN) == 0 {
+		log.Fatal("No DSN environment variable found")
+	}
+
+	REDISADDR := os.Getenv("REDISADDR")
+	if len(REDISADDR) == 0 {
+		log.Fatal("No REDISADDR environment variable found")
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: REDISADDR,
+	})
+	publisher := events.NewRedisPublisher(redisClient, "events")
+
+	db, err := sql.Open("mysql", DSN)
+	if err != nil {
+		log.Fatalf("error opening db: %v", err)
+	}
+
+	// Wait for Redis and the database to start up
+	pings := map[string]func(context.Context) error{
+		"mysql": db.PingContext,
+		"redis": func(ctx context.Context) error { return redisClient.Ping(ctx).Err() },
+	}
+	for name, ping := range pings {
+		err := waitFor(name, ping)
+		if err != nil {
+			log.Fatalf("error pinging %s: %v", name, err)
+		}
+	}
+
+	mysqlStore, err := channels.NewMySQLStore(db)
+	if err != nil {
+		log.Fatalf("error creating mysql store: %v", err)
+	}
+
+	hctx := handlers.NewHandlerContext(&mysqlStore, &publisher)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/channels", hctx.ChannelsHandler)
+	mux.HandleFunc("/v1/channels/{ChannelID}", hctx.SpecificChannelHandler)
+	mux.HandleFunc("/v1/channels/{ChannelID}/members", hctx.MembersHandler)
+	mux.HandleFunc("/v1/messages/{MessageID}", hctx.SpecificMessageHandler)
+
+	log.Printf("server is listening at %s...", ADDR)
+	log.Fatal(http.ListenAndServe(ADDR, mux))
+}
@@ -0,0 +1,90 @@
+package channels
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxNameLength = 255
+
+type Channel struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Private     bool       `json:"private"`
+	Members     []int      `json:"members"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CreatorID   int        `json:"creatorId"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+}
+
+func (c *Channel) IsMember(userID int) bool {
+	return slices.Contains(c.Members, userID)
+}
+
+func (c *Channel) CanView(userID int) bool {
+	return !c.Private || c.IsMember(userID)
+}
+
+type NewChannel struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	Members     []int  `json:"members"`
+}
+
+func (nc *NewChannel) Validate() error {
+	name := strings.TrimSpace(nc.Name)
+	if name == "" {
+		return errors.New("channel name cannot be blank")
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("channel name cannot be longer than %d characters", maxNameLength)
+	}
+	return nil
+}
+
+func (nc *NewChannel) ToChannel(creatorID int) *Channel {
+	members := []int{creatorID}
+	for _, id := range nc.Members {
+		if !slices.Contains(members, id) {
+			members = append(members, id)
+		}
+	}
+
+	return &Channel{
+		Name:        strings.TrimSpace(nc.Name),
+		Description: nc.Description,
+		Private:     nc.Private,
+		Members:     members,
+		CreatedAt:   time.Now(),
+		CreatorID:   creatorID,
+	}
+}
+
+type ChannelUpdates struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (c *Channel) ApplyUpdates(updates *ChannelUpdates) error {
+	if updates.Name != "" {
+		name := strings.TrimSpace(updates.Name)
+		if name == "" {
+			return errors.New("channel name cannot be blank")
+		}
+		if len(name) > maxNameLength {
+			return fmt.Errorf("channel name cannot be longer than %d characters", maxNameLength)
+		}
+		c.Name = name
+	}
+	if updates.Description != "" {
+		c.Description = updates.Description
+	}
+	now := time.Now()
+	c.EditedAt = &now
+	return nil
+}
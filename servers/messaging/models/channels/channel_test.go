@@ -0,0 +1,116 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewChannelValidate(t *testing.T) {
+	cases := []struct {
+		input  *NewChannel
+		output string
+	}{
+		{&NewChannel{Name: "general"}, "valid"},
+		{&NewChannel{Name: "random", Description: "anything goes", Private: true}, "valid"},
+		{&NewChannel{}, "invalid"},
+		{&NewChannel{Name: "   "}, "invalid"},
+		{&NewChannel{Name: strings.Repeat("a", 256)}, "invalid"},
+		{&NewChannel{Name: " " + strings.Repeat("a", 255) + " "}, "valid"},
+	}
+
+	for _, c := range cases {
+		err := c.input.Validate()
+		if (err == nil && c.output == "invalid") || (err != nil && c.output == "valid") {
+			t.Errorf("incorrect output for `%v`: expected `%s`", c.input, c.output)
+		}
+	}
+}
+
+func TestToChannel(t *testing.T) {
+	nc := &NewChannel{Name: " general ", Private: true, Members: []int{2, 1, 3, 2}}
+	channel := nc.ToChannel(1)
+
+	if channel.Name != "general" {
+		t.Errorf("Expected Name `general` but got `%s`", channel.Name)
+	}
+	if channel.CreatorID != 1 {
+		t.Errorf("Expected CreatorID 1 but got %d", channel.CreatorID)
+	}
+	if len(channel.Members) != 3 || channel.Members[0] != 1 {
+		t.Errorf("Expected creator first and no duplicate members but got %v", channel.Members)
+	}
+	if channel.CreatedAt.IsZero() {
+		t.Error("Expected CreatedAt to be set")
+	}
+}
+
+func TestCanView(t *testing.T) {
+	public := &Channel{Members: []int{1}}
+	private := &Channel{Private: true, Members: []int{1}}
+
+	if !public.CanView(2) {
+		t.Error("Expected anyone to be able to view a public channel")
+	}
+	if !private.CanView(1) {
+		t.Error("Expected a member to be able to view a private channel")
+	}
+	if private.CanView(2) {
+		t.Error("Expected a non-member not to be able to view a private channel")
+	}
+}
+
+func TestChannelApplyUpdates(t *testing.T) {
+	channel := &Channel{Name: "general", Description: "old"}
+	err := channel.ApplyUpdates(&ChannelUpdates{Description: "new"})
+	if err != nil {
+		t.Errorf("Error applying updates: %s", err)
+	}
+	if channel.Name != "general" || channel.Description != "new" {
+		t.Errorf("Expected only the description to change but got %v", channel)
+	}
+	if channel.EditedAt == nil {
+		t.Error("Expected EditedAt to be set")
+	}
+
+	err = channel.ApplyUpdates(&ChannelUpdates{Name: strings.Repeat("a", 256)})
+	if err == nil {
+		t.Error("Expected an error for a name that is too long")
+	}
+
+	err = channel.ApplyUpdates(&ChannelUpdates{Name: " " + strings.Repeat("a", 255) + " "})
+	if err != nil {
+		t.Errorf("Expected surrounding whitespace not to count towards the name length but got %s", err)
+	}
+	if channel.Name != strings.Repeat("a", 255) {
+		t.Errorf("Expected the name to be trimmed but got %q", channel.Name)
+	}
+
+	err = channel.ApplyUpdates(&ChannelUpdates{Name: "   "})
+	if err == nil {
+		t.Error("Expected an error for a blank name")
+	}
+	if channel.Name != strings.Repeat("a", 255) {
+		t.Errorf("Expected a blank name to leave the name unchanged but got %s", channel.Name)
+	}
+}
+
+func TestMessageValidateAndUpdate(t *testing.T) {
+	if err := (&NewMessage{Body: " "}).Validate(); err == nil {
+		t.Error("Expected a blank message to be invalid")
+	}
+
+	message := (&NewMessage{Body: "hello"}).ToMessage(4, 2)
+	if message.ChannelID != 4 || message.CreatorID != 2 {
+		t.Errorf("Expected channel 4 and creator 2 but got %d and %d", message.ChannelID, message.CreatorID)
+	}
+
+	if err := message.ApplyUpdates(&MessageUpdates{}); err == nil {
+		t.Error("Expected an error when editing a message to be blank")
+	}
+	if err := message.ApplyUpdates(&MessageUpdates{Body: "goodbye"}); err != nil {
+		t.Errorf("Error applying updates: %s", err)
+	}
+	if message.Body != "goodbye" || message.EditedAt == nil {
+		t.Errorf("Expected body to be updated and EditedAt set but got %v", message)
+	}
+}
@@ -0,0 +1,50 @@
+package channels
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	ID        int        `json:"id"`
+	ChannelID int        `json:"channelId"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"createdAt"`
+	CreatorID int        `json:"creatorId"`
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+}
+
+type NewMessage struct {
+	Body string `json:"body"`
+}
+
+func (nm *NewMessage) Validate() error {
+	if strings.TrimSpace(nm.Body) == "" {
+		return errors.New("message body cannot be blank")
+	}
+	return nil
+}
+
+func (nm *NewMessage) ToMessage(channelID int, creatorID int) *Message {
+	return &Message{
+		ChannelID: channelID,
+		Body:      nm.Body,
+		CreatedAt: time.Now(),
+		CreatorID: creatorID,
+	}
+}
+
+type MessageUpdates struct {
+	Body string `json:"body"`
+}
+
+func (m *Message) ApplyUpdates(updates *MessageUpdates) error {
+	if strings.TrimSpace(updates.Body) == "" {
+		return errors.New("message body cannot be blank")
+	}
+	m.Body = updates.Body
+	now := time.Now()
+	m.EditedAt = &now
+	return nil
+}
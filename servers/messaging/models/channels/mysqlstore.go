@@ -0,0 +1,277 @@
+package channels
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+const channelColumns = "id, name, description, private, created_at, creator_id, edited_at"
+const messageColumns = "id, channel_id, body, created_at, creator_id, edited_at"
+
+type MySQLStore struct {
+	db *sql.DB
+}
+
+func NewMySQLStore(db *sql.DB) (MySQLStore, error) {
+	if db == nil {
+		return MySQLStore{}, fmt.Errorf("db must not be nil")
+	}
+	return MySQLStore{db: db}, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanChannel(row scanner) (*Channel, error) {
+	channel := Channel{}
+	var description sql.NullString
+	var editedAt sql.NullTime
+	err := row.Scan(&channel.ID, &channel.Name, &description, &channel.Private, &channel.CreatedAt, &channel.CreatorID, &editedAt)
+	if err != nil {
+		return nil, err
+	}
+	channel.Description = description.String
+	if editedAt.Valid {
+		channel.EditedAt = &editedAt.Time
+	}
+	return &channel, nil
+}
+
+func scanMessage(row scanner) (*Message, error) {
+	message := Message{}
+	var editedAt sql.NullTime
+	err := row.Scan(&message.ID, &message.ChannelID, &message.Body, &message.CreatedAt, &message.CreatorID, &editedAt)
+	if err != nil {
+		return nil, err
+	}
+	if editedAt.Valid {
+		message.EditedAt = &editedAt.Time
+	}
+	return &message, nil
+}
+
+func (s *MySQLStore) getMembers(channelID int) ([]int, error) {
+	mq := "SELECT user_id FROM channel_members WHERE channel_id = ? ORDER BY user_id"
+	rows, err := s.db.Query(mq, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []int{}
+	for rows.Next() {
+		var userID int
+		err = rows.Scan(&userID)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, userID)
+	}
+	return members, rows.Err()
+}
+
+func (s *MySQLStore) GetChannels(userID int) ([]*Channel, error) {
+	gq := "SELECT " + channelColumns + " FROM channels WHERE private = FALSE OR id IN (SELECT channel_id FROM channel_members WHERE user_id = ?) ORDER BY id"
+	rows, err := s.db.Query(gq, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []*Channel{}
+	for rows.Next() {
+		channel, err := scanChannel(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		channel.Members, err = s.getMembers(channel.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return channels, nil
+}
+
+func (s *MySQLStore) GetChannel(id int) (*Channel, error) {
+	gq := "SELECT " + channelColumns + " FROM channels WHERE id = ?"
+	channel, err := scanChannel(s.db.QueryRow(gq, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	channel.Members, err = s.getMembers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+func (s *MySQLStore) InsertChannel(channel *Channel) (*Channel, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insq := "INSERT INTO channels(name, description, private, created_at, creator_id) VALUES(?,?,?,?,?)"
+	res, err := tx.Exec(insq, channel.Name, channel.Description, channel.Private, channel.CreatedAt, channel.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	memq := "INSERT INTO channel_members(channel_id, user_id) VALUES(?,?)"
+	for _, userID := range channel.Members {
+		_, err = tx.Exec(memq, id, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	channel.ID = int(id)
+	return channel, nil
+}
+
+func (s *MySQLStore) UpdateChannel(id int, channel *Channel) (*Channel, error) {
+	uq := "UPDATE channels SET name = ?, description = ?, edited_at = ? WHERE id = ?"
+	_, err := s.db.Exec(uq, channel.Name, channel.Description, channel.EditedAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetChannel(id)
+}
+
+func (s *MySQLStore) DeleteChannel(id int) error {
+	dq := "DELETE FROM channels WHERE id = ?"
+	res, err := s.db.Exec(dq, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func (s *MySQLStore) AddMember(channelID int, userID int) error {
+	aq := "INSERT IGNORE INTO channel_members(channel_id, user_id) VALUES(?,?)"
+	_, err := s.db.Exec(aq, channelID, userID)
+	return err
+}
+
+func (s *MySQLStore) RemoveMember(channelID int, userID int) error {
+	rq := "DELETE FROM channel_members WHERE channel_id = ? AND user_id = ?"
+	_, err := s.db.Exec(rq, channelID, userID)
+	return err
+}
+
+func (s *MySQLStore) GetMessages(channelID int, before int, limit int) ([]*Message, error) {
+	var rows *sql.Rows
+	var err error
+	if before > 0 {
+		gq := "SELECT " + messageColumns + " FROM messages WHERE channel_id = ? AND id < ? ORDER BY id DESC LIMIT ?"
+		rows, err = s.db.Query(gq, channelID, before, limit)
+	} else {
+		gq := "SELECT " + messageColumns + " FROM messages WHERE channel_id = ? ORDER BY id DESC LIMIT ?"
+		rows, err = s.db.Query(gq, channelID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *MySQLStore) GetMessage(id int) (*Message, error) {
+	gq := "SELECT " + messageColumns + " FROM messages WHERE id = ?"
+	message, err := scanMessage(s.db.QueryRow(gq, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *MySQLStore) InsertMessage(message *Message) (*Message, error) {
+	insq := "INSERT INTO messages(channel_id, body, created_at, creator_id) VALUES(?,?,?,?)"
+	res, err := s.db.Exec(insq, message.ChannelID, message.Body, message.CreatedAt, message.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	message.ID = int(id)
+	return message, nil
+}
+
+func (s *MySQLStore) UpdateMessage(id int, message *Message) (*Message, error) {
+	uq := "UPDATE messages SET body = ?, edited_at = ? WHERE id = ?"
+	_, err := s.db.Exec(uq, message.Body, message.EditedAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMessage(id)
+}
+
+func (s *MySQLStore) DeleteMessage(id int) error {
+	dq := "DELETE FROM messages WHERE id = ?"
+	res, err := s.db.Exec(dq, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
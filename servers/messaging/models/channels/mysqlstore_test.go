@@ -0,0 +1,217 @@
+package channels
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var channelFixture = &Channel{
+	Name:        "general",
+	Description: "everyone",
+	Private:     true,
+	Members:     []int{1, 2},
+	CreatedAt:   created,
+	CreatorID:   1,
+}
+
+var channelRowColumns = []string{"id", "name", "description", "private", "created_at", "creator_id", "edited_at"}
+var messageRowColumns = []string{"id", "channel_id", "body", "created_at", "creator_id", "edited_at"}
+
+func TestShouldInsertChannelWithMembers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO channels").WithArgs(channelFixture.Name, channelFixture.Description, channelFixture.Private, channelFixture.CreatedAt, channelFixture.CreatorID).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO channel_members").WithArgs(7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO channel_members").WithArgs(7, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := MySQLStore{db: db}
+	channel, err := store.InsertChannel(channelFixture)
+	if err != nil {
+		t.Errorf("Error inserting channel: %s", err)
+	}
+	if channel.ID != 7 {
+		t.Errorf("Expected ID to be auto-assigned to 7 but got %d", channel.ID)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldRollbackFailedChannelInsert(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO channels").WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO channel_members").WillReturnError(errors.New(""))
+	mock.ExpectRollback()
+
+	store := MySQLStore{db: db}
+	_, err := store.InsertChannel(channelFixture)
+	if err == nil {
+		t.Errorf("Expected insertion error")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldSelectChannelWithMembers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	data := sqlmock.NewRows(channelRowColumns)
+	data.AddRow(7, "general", nil, true, created, 1, nil)
+	mock.ExpectQuery("SELECT (.+) FROM channels").WithArgs(7).WillReturnRows(data)
+	mock.ExpectQuery("SELECT user_id FROM channel_members").WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(2))
+
+	store := MySQLStore{db: db}
+	channel, err := store.GetChannel(7)
+	if err != nil {
+		t.Fatalf("Error fetching channel from database: %s", err)
+	}
+	if channel.ID != 7 || channel.Description != "" || channel.EditedAt != nil {
+		t.Errorf("Unexpected channel %v", channel)
+	}
+	if !channel.IsMember(2) {
+		t.Errorf("Expected user 2 to be a member but got %v", channel.Members)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldHandleMissingChannel(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM channels").WithArgs(7).WillReturnRows(sqlmock.NewRows(channelRowColumns))
+
+	store := MySQLStore{db: db}
+	_, err := store.GetChannel(7)
+	if !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("Expected ErrChannelNotFound but got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldSelectVisibleChannels(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	data := sqlmock.NewRows(channelRowColumns)
+	data.AddRow(1, "general", "everyone", false, created, 1, nil)
+	data.AddRow(2, "secret", "", true, created, 2, created)
+	mock.ExpectQuery("SELECT (.+) FROM channels WHERE private = FALSE").WithArgs(2).WillReturnRows(data)
+	mock.ExpectQuery("SELECT user_id FROM channel_members").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("SELECT user_id FROM channel_members").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+
+	store := MySQLStore{db: db}
+	channels, err := store.GetChannels(2)
+	if err != nil {
+		t.Fatalf("Error fetching channels from database: %s", err)
+	}
+	if len(channels) != 2 {
+		t.Fatalf("Expected 2 channels but got %d", len(channels))
+	}
+	if channels[1].EditedAt == nil {
+		t.Error("Expected EditedAt to be set on the second channel")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldDeleteChannel(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM channels").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM channels").WithArgs(8).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	store := MySQLStore{db: db}
+	if err := store.DeleteChannel(7); err != nil {
+		t.Errorf("Error deleting channel: %s", err)
+	}
+	if err := store.DeleteChannel(8); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("Expected ErrChannelNotFound but got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldInsertMessage(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	message := &Message{ChannelID: 7, Body: "hello", CreatedAt: created, CreatorID: 1}
+	mock.ExpectExec("INSERT INTO messages").WithArgs(7, "hello", created, 1).WillReturnResult(sqlmock.NewResult(3, 1))
+
+	store := MySQLStore{db: db}
+	message, err := store.InsertMessage(message)
+	if err != nil {
+		t.Errorf("Error inserting message: %s", err)
+	}
+	if message.ID != 3 {
+		t.Errorf("Expected ID to be auto-assigned to 3 but got %d", message.ID)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldSelectMessagesBefore(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	data := sqlmock.NewRows(messageRowColumns)
+	data.AddRow(4, 7, "second", created, 1, nil)
+	data.AddRow(3, 7, "first", created, 2, created)
+	mock.ExpectQuery("SELECT (.+) FROM messages WHERE channel_id = (.+) AND id <").WithArgs(7, 5, 100).WillReturnRows(data)
+
+	store := MySQLStore{db: db}
+	messages, err := store.GetMessages(7, 5, 100)
+	if err != nil {
+		t.Fatalf("Error fetching messages from database: %s", err)
+	}
+	if len(messages) != 2 || messages[0].ID != 4 {
+		t.Errorf("Expected newest message first but got %v", messages)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
+
+func TestShouldUpdateMessage(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	message := &Message{ID: 3, ChannelID: 7, Body: "edited", CreatedAt: created, CreatorID: 1, EditedAt: &created}
+	mock.ExpectExec("UPDATE messages").WithArgs("edited", &created, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	data := sqlmock.NewRows(messageRowColumns)
+	data.AddRow(3, 7, "edited", created, 1, created)
+	mock.ExpectQuery("SELECT (.+) FROM messages").WithArgs(3).WillReturnRows(data)
+
+	store := MySQLStore{db: db}
+	updated, err := store.UpdateMessage(3, message)
+	if err != nil {
+		t.Fatalf("Error updating message: %s", err)
+	}
+	if updated.Body != "edited" {
+		t.Errorf("Expected body `edited` but got `%s`", updated.Body)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %s", err)
+	}
+}
@@ -0,0 +1,22 @@
+package channels
+
+import "errors"
+
+var ErrChannelNotFound = errors.New("channel was not found")
+var ErrMessageNotFound = errors.New("message was not found")
+
+type Store interface {
+	GetChannels(userID int) ([]*Channel, error)
+	GetChannel(id int) (*Channel, error)
+	InsertChannel(channel *Channel) (*Channel, error)
+	UpdateChannel(id int, channel *Channel) (*Channel, error)
+	DeleteChannel(id int) error
+	AddMember(channelID int, userID int) error
+	RemoveMember(channelID int, userID int) error
+
+	GetMessages(channelID int, before int, limit int) ([]*Message, error)
+	GetMessage(id int) (*Message, error)
+	InsertMessage(message *Message) (*Message, error)
+	UpdateMessage(id int, message *Message) (*Message, error)
+	DeleteMessage(id int) error
+}
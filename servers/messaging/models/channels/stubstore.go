@@ -0,0 +1,152 @@
+package channels
+
+import "slices"
+
+type StubStore struct {
+	channels      map[int]*Channel
+	messages      map[int]*Message
+	channelSerial int
+	messageSerial int
+}
+
+func NewStubStore() *StubStore {
+	return &StubStore{
+		channels:      make(map[int]*Channel),
+		messages:      make(map[int]*Message),
+		channelSerial: 1,
+		messageSerial: 1,
+	}
+}
+
+func (s *StubStore) GetChannels(userID int) ([]*Channel, error) {
+	result := []*Channel{}
+	for id := 1; id < s.channelSerial; id++ {
+		channel, exists := s.channels[id]
+		if exists && channel.CanView(userID) {
+			result = append(result, channel)
+		}
+	}
+	return result, nil
+}
+
+func (s *StubStore) GetChannel(id int) (*Channel, error) {
+	channel, exists := s.channels[id]
+	if !exists {
+		return nil, ErrChannelNotFound
+	}
+	return channel, nil
+}
+
+func (s *StubStore) InsertChannel(channel *Channel) (*Channel, error) {
+	channel.ID = s.channelSerial
+	s.channelSerial++
+	s.channels[channel.ID] = channel
+	return channel, nil
+}
+
+func (s *StubStore) UpdateChannel(id int, channel *Channel) (*Channel, error) {
+	existingChannel, err := s.GetChannel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	existingChannel.Name = channel.Name
+	existingChannel.Description = channel.Description
+	existingChannel.EditedAt = channel.EditedAt
+
+	return existingChannel, nil
+}
+
+func (s *StubStore) DeleteChannel(id int) error {
+	_, err := s.GetChannel(id)
+	if err != nil {
+		return err
+	}
+
+	for messageID, message := range s.messages {
+		if message.ChannelID == id {
+			delete(s.messages, messageID)
+		}
+	}
+	delete(s.channels, id)
+	return nil
+}
+
+func (s *StubStore) AddMember(channelID int, userID int) error {
+	channel, err := s.GetChannel(channelID)
+	if err != nil {
+		return err
+	}
+
+	if !channel.IsMember(userID) {
+		channel.Members = append(channel.Members, userID)
+	}
+	return nil
+}
+
+func (s *StubStore) RemoveMember(channelID int, userID int) error {
+	channel, err := s.GetChannel(channelID)
+	if err != nil {
+		return err
+	}
+
+	channel.Members = slices.DeleteFunc(channel.Members, func(id int) bool {
+		return id == userID
+	})
+	return nil
+}
+
+func (s *StubStore) GetMessages(channelID int, before int, limit int) ([]*Message, error) {
+	result := []*Message{}
+	for id := s.messageSerial - 1; id > 0 && len(result) < limit; id-- {
+		if before > 0 && id >= before {
+			continue
+		}
+		message, exists := s.messages[id]
+		if exists && message.ChannelID == channelID {
+			result = append(result, message)
+		}
+	}
+	return result, nil
+}
+
+func (s *StubStore) GetMessage(id int) (*Message, error) {
+	message, exists := s.messages[id]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+func (s *StubStore) InsertMessage(message *Message) (*Message, error) {
+	_, err := s.GetChannel(message.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	message.ID = s.messageSerial
+	s.messageSerial++
+	s.messages[message.ID] = message
+	return message, nil
+}
+
+func (s *StubStore) UpdateMessage(id int, message *Message) (*Message, error) {
+	existingMessage, err := s.GetMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	existingMessage.Body = message.Body
+	existingMessage.EditedAt = message.EditedAt
+
+	return existingMessage, nil
+}
+
+func (s *StubStore) DeleteMessage(id int) error {
+	_, err := s.GetMessage(id)
+	if err != nil {
+		return err
+	}
+	delete(s.messages, id)
+	return nil
+}